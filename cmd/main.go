@@ -2,20 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/brunofjesus/raspberry-bookshelf/internal/config"
 	"github.com/brunofjesus/raspberry-bookshelf/internal/service"
 )
 
 func main() {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	// the level was already checked by the configuration validation
+	level, _ := cfg.Log.SlogLevel()
 	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level: level,
 	}))
 	slog.SetDefault(log)
 
-	app := service.New()
-	if err := app.Run(context.Background()); err != nil {
-		panic(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app := service.New(ctx, cfg)
+	if err := app.Run(ctx); err != nil {
+		slog.Error("The service stopped with an error", slog.Any("error", err))
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,318 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"gopkg.in/yaml.v3"
+)
+
+// coverExtensions are the extensions of the images used as covers, in
+// order of preference.
+var coverExtensions = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+// sidecarExtensions are the extensions of the files holding the metadata
+// of a PDF, in order of preference.
+var sidecarExtensions = []string{".json", ".yaml", ".yml"}
+
+// sidecar is the metadata of a PDF stored next to it, such as
+// Issue_01.pdf and Issue_01.yaml.
+type sidecar struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// cachedInfo is the document information of a PDF, kept while the file
+// does not change so it is not read on every refresh.
+type cachedInfo struct {
+	size    int64
+	modTime time.Time
+	info    pdfInfo
+}
+
+// DirectoryAPI is an adapter listing the PDFs of a local directory.
+// The first level of subdirectories are the categories, the PDFs directly
+// in the directory use the name of the directory as their category.
+// The files are served by the application under /files/{source}/.
+type DirectoryAPI struct {
+	source string
+	root   string
+
+	mu    sync.Mutex
+	infos map[string]cachedInfo
+}
+
+// NewDirectoryAPI creates a new instance of DirectoryAPI listing the PDFs
+// found in root. source is the name of the source, it is part of the URL
+// of the files.
+func NewDirectoryAPI(source, root string) *DirectoryAPI {
+	return &DirectoryAPI{
+		source: source,
+		root:   root,
+		infos:  map[string]cachedInfo{},
+	}
+}
+
+// GetBooks lists the PDFs of the directory.
+func (d *DirectoryAPI) GetBooks(ctx context.Context) ([]entities.Book, error) {
+	var books []entities.Book
+	seen := map[string]bool{}
+
+	err := d.walk(func(rel string, entry fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !strings.EqualFold(path.Ext(rel), ".pdf") {
+			return nil
+		}
+		seen[rel] = true
+		books = append(books, d.book(ctx, rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list directory %s: %w", d.root, err)
+	}
+
+	d.mu.Lock()
+	for rel := range d.infos {
+		if !seen[rel] {
+			delete(d.infos, rel)
+		}
+	}
+	d.mu.Unlock()
+
+	return books, nil
+}
+
+// Lookup returns the path of a file of the directory from its name in the
+// URL. Only PDFs and cover images are served.
+func (d *DirectoryAPI) Lookup(name string) (string, bool) {
+	if !fs.ValidPath(name) || isHidden(name) {
+		return "", false
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if ext != ".pdf" && !slices.Contains(coverExtensions, ext) {
+		return "", false
+	}
+
+	filePath := filepath.Join(d.root, filepath.FromSlash(name))
+	info, err := os.Stat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return filePath, true
+}
+
+// Watch polls the directory and calls onChange when a file is added,
+// removed or modified, until the provided context is done.
+// This operation is blocking, you might want to run it in a separate goroutine.
+func (d *DirectoryAPI) Watch(ctx context.Context, interval time.Duration, onChange func()) error {
+	last, err := d.fingerprint()
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot scan directory", slog.String("dir", d.root), slog.Any("error", err))
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("context is done, stop watching directory", slog.String("dir", d.root))
+			return nil
+		case <-timer.C:
+			current, err := d.fingerprint()
+			if err != nil {
+				slog.ErrorContext(ctx, "cannot scan directory", slog.String("dir", d.root), slog.Any("error", err))
+			} else if current != last {
+				slog.InfoContext(ctx, "directory changed", slog.String("dir", d.root))
+				last = current
+				onChange()
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// fingerprint summarizes the names, sizes and modification times of the
+// files of the directory.
+func (d *DirectoryAPI) fingerprint() (string, error) {
+	hash := sha256.New()
+	err := d.walk(func(rel string, entry fs.DirEntry) error {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(hash, "%s\x00%d\x00%d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return err
+	})
+	return hex.EncodeToString(hash.Sum(nil)), err
+}
+
+// walk calls fn for every regular file of the directory, hidden files and
+// directories are skipped. rel is the slash separated path of the file
+// relative to the root.
+func (d *DirectoryAPI) walk(fn func(rel string, entry fs.DirEntry) error) error {
+	return filepath.WalkDir(d.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if isHidden(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return fn(rel, entry)
+	})
+}
+
+// book builds the book of the PDF at rel.
+// The title and description come from the sidecar file, or from the PDF
+// document information, or the title is derived from the file name.
+func (d *DirectoryAPI) book(ctx context.Context, rel string) entities.Book {
+	base := strings.TrimSuffix(rel, path.Ext(rel))
+
+	meta, err := d.readSidecar(base)
+	if err != nil {
+		slog.WarnContext(ctx, "cannot read sidecar file", slog.String("file", rel), slog.Any("error", err))
+	}
+	if meta.Title == "" || meta.Description == "" {
+		info := d.readInfo(ctx, rel)
+		if meta.Title == "" {
+			meta.Title = info.Title
+		}
+		if meta.Description == "" {
+			meta.Description = info.Subject
+		}
+	}
+	if meta.Title == "" {
+		meta.Title = titleFromFileName(path.Base(base))
+	}
+
+	category := filepath.Base(d.root)
+	if dir, _, ok := strings.Cut(rel, "/"); ok {
+		category = dir
+	}
+
+	book := entities.Book{
+		Title:        meta.Title,
+		Description:  meta.Description,
+		Link:         d.fileURL(rel),
+		Category:     category,
+		File:         path.Base(rel),
+		Availability: entities.AvailabilityAvailable,
+	}
+	for _, ext := range coverExtensions {
+		if _, ok := d.Lookup(base + ext); ok {
+			book.Cover = d.fileURL(base + ext)
+			break
+		}
+	}
+	return book
+}
+
+// readSidecar reads the metadata stored next to the PDF, base is the path
+// of the PDF without extension. A missing sidecar is not an error.
+func (d *DirectoryAPI) readSidecar(base string) (sidecar, error) {
+	var meta sidecar
+	for _, ext := range sidecarExtensions {
+		data, err := os.ReadFile(filepath.Join(d.root, filepath.FromSlash(base+ext)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return meta, err
+		}
+		if ext == ".json" {
+			err = json.Unmarshal(data, &meta)
+		} else {
+			err = yaml.Unmarshal(data, &meta)
+		}
+		return meta, err
+	}
+	return meta, nil
+}
+
+// readInfo returns the document information of the PDF at rel.
+func (d *DirectoryAPI) readInfo(ctx context.Context, rel string) pdfInfo {
+	filePath := filepath.Join(d.root, filepath.FromSlash(rel))
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return pdfInfo{}
+	}
+
+	d.mu.Lock()
+	cached, ok := d.infos[rel]
+	d.mu.Unlock()
+	if ok && cached.size == stat.Size() && cached.modTime.Equal(stat.ModTime()) {
+		return cached.info
+	}
+
+	var info pdfInfo
+	f, err := os.Open(filePath)
+	if err == nil {
+		info, err = readPDFInfo(f, stat.Size())
+		_ = f.Close()
+	}
+	if err != nil && !errors.Is(err, errNoPDFInfo) {
+		slog.WarnContext(ctx, "cannot read PDF information", slog.String("file", rel), slog.Any("error", err))
+	}
+
+	d.mu.Lock()
+	d.infos[rel] = cachedInfo{size: stat.Size(), modTime: stat.ModTime(), info: info}
+	d.mu.Unlock()
+	return info
+}
+
+// fileURL returns the URL the application serves the file at rel from.
+func (d *DirectoryAPI) fileURL(rel string) string {
+	segments := strings.Split(rel, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return "/files/" + url.PathEscape(d.source) + "/" + strings.Join(segments, "/")
+}
+
+// titleFromFileName turns a file name such as Training_Guide-2 into
+// "Training Guide 2".
+func titleFromFileName(name string) string {
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' '
+	}), " ")
+}
+
+func isHidden(name string) bool {
+	for s := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(s, ".") {
+			return true
+		}
+	}
+	return false
+}
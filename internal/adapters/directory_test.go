@@ -0,0 +1,142 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+}
+
+func newTestDirectory(t *testing.T) (*DirectoryAPI, string) {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "Library")
+
+	writeFile(t, root, "HackSpace/HS_01.pdf", "%PDF-1.4\n")
+	writeFile(t, root, "HackSpace/HS_01.json", `{"title": "HackSpace 1", "description": "The first issue"}`)
+	writeFile(t, root, "HackSpace/HS_01.jpg", "jpeg")
+	writeFile(t, root, "Wireframe/WF-02.pdf",
+		"%PDF-1.4\n5 0 obj\n<< /Title (Wireframe 2) /Subject (Games) >>\nendobj\ntrailer << /Info 5 0 R >>\n")
+	writeFile(t, root, "Wireframe/WF-02.png", "png")
+	writeFile(t, root, "Training/Intro.pdf", "%PDF-1.4\n")
+	writeFile(t, root, "Training/Intro.yaml", "title: Introduction\n")
+	writeFile(t, root, "Onboarding_Guide.pdf", "%PDF-1.4\n")
+	writeFile(t, root, "notes.txt", "ignored")
+	writeFile(t, root, ".trash/Deleted.pdf", "%PDF-1.4\n")
+
+	return NewDirectoryAPI("nas", root), root
+}
+
+func TestDirectoryGetBooks(t *testing.T) {
+	subject, _ := newTestDirectory(t)
+
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []entities.Book{
+		{
+			Title:        "HackSpace 1",
+			Description:  "The first issue",
+			Cover:        "/files/nas/HackSpace/HS_01.jpg",
+			Link:         "/files/nas/HackSpace/HS_01.pdf",
+			Category:     "HackSpace",
+			File:         "HS_01.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "Wireframe 2",
+			Description:  "Games",
+			Cover:        "/files/nas/Wireframe/WF-02.png",
+			Link:         "/files/nas/Wireframe/WF-02.pdf",
+			Category:     "Wireframe",
+			File:         "WF-02.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "Introduction",
+			Link:         "/files/nas/Training/Intro.pdf",
+			Category:     "Training",
+			File:         "Intro.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "Onboarding Guide",
+			Link:         "/files/nas/Onboarding_Guide.pdf",
+			Category:     "Library",
+			File:         "Onboarding_Guide.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+	}, books)
+}
+
+func TestDirectoryGetBooksMissingRoot(t *testing.T) {
+	subject := NewDirectoryAPI("nas", filepath.Join(t.TempDir(), "missing"))
+	_, err := subject.GetBooks(t.Context())
+	assert.Error(t, err)
+}
+
+func TestDirectoryLookup(t *testing.T) {
+	subject, root := newTestDirectory(t)
+
+	p, ok := subject.Lookup("HackSpace/HS_01.pdf")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "HackSpace", "HS_01.pdf"), p)
+
+	_, ok = subject.Lookup("Wireframe/WF-02.png")
+	assert.True(t, ok)
+
+	for _, name := range []string{
+		"HackSpace/HS_01.json",
+		"notes.txt",
+		"../Library/HackSpace/HS_01.pdf",
+		"/HackSpace/HS_01.pdf",
+		".trash/Deleted.pdf",
+		"HackSpace/missing.pdf",
+	} {
+		_, ok := subject.Lookup(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestDirectoryWatch(t *testing.T) {
+	subject, root := newTestDirectory(t)
+
+	var changes atomic.Int64
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- subject.Watch(ctx, 5*time.Millisecond, func() { changes.Add(1) })
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, changes.Load(), "nothing changed yet")
+
+	writeFile(t, root, "Training/Advanced.pdf", "%PDF-1.4\n")
+	require.Eventually(t, func() bool {
+		return changes.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("watch did not stop after the context was cancelled")
+	}
+}
+
+func TestTitleFromFileName(t *testing.T) {
+	assert.Equal(t, "Training Guide 2", titleFromFileName("Training_Guide-2"))
+}
@@ -0,0 +1,17 @@
+package adapters
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// StatusError is returned when a source answers with an unexpected HTTP
+// status, the response body is not decoded.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s from %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
@@ -0,0 +1,305 @@
+package adapters
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+// FeedAPI is an adapter for fetching the PDFs published as enclosures of an
+// RSS 2.0 or Atom feed, the way podcasts publish their episodes.
+type FeedAPI struct {
+	httpClient *http.Client
+	feedURL    string
+	category   string
+}
+
+// FeedXML represents an RSS 2.0 or an Atom feed. Only the fields of the
+// matching format are filled, the root element tells which one it is.
+type FeedXML struct {
+	XMLName xml.Name
+	// RSS 2.0
+	Channel FeedChannel `xml:"channel"`
+	// Atom
+	Title      string         `xml:"title"`
+	Categories []FeedCategory `xml:"category"`
+	Entries    []FeedEntry    `xml:"entry"`
+}
+
+// FeedChannel represents the channel of an RSS 2.0 feed.
+type FeedChannel struct {
+	Title       string         `xml:"title"`
+	Categories  []FeedCategory `xml:"category"`
+	ItunesImage FeedImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	Items       []FeedItem     `xml:"item"`
+}
+
+// FeedItem represents an item of an RSS 2.0 feed.
+type FeedItem struct {
+	Title       string          `xml:"title"`
+	Description string          `xml:"description"`
+	Summary     string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	Enclosures  []FeedEnclosure `xml:"enclosure"`
+	Categories  []FeedCategory  `xml:"category"`
+	FeedMedia
+}
+
+// FeedEntry represents an entry of an Atom feed.
+type FeedEntry struct {
+	Title      string         `xml:"title"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Links      []FeedLink     `xml:"link"`
+	Categories []FeedCategory `xml:"category"`
+	FeedMedia
+}
+
+// FeedMedia holds the covers an RSS item or an Atom entry may carry.
+type FeedMedia struct {
+	Thumbnails      []FeedThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	GroupThumbnails []FeedThumbnail `xml:"http://search.yahoo.com/mrss/ group>thumbnail"`
+	ItunesImage     FeedImage       `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// FeedEnclosure represents the enclosure of an RSS 2.0 item.
+type FeedEnclosure struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// FeedLink represents a link of an Atom entry, enclosures use the enclosure
+// relation.
+type FeedLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// FeedCategory represents a category, RSS 2.0 stores it as text while Atom
+// stores it in the term and label attributes.
+type FeedCategory struct {
+	Text  string `xml:",chardata"`
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// FeedThumbnail represents a media:thumbnail element.
+type FeedThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// FeedImage represents an itunes:image element.
+type FeedImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// Name returns the name of the category.
+func (c FeedCategory) Name() string {
+	for _, name := range []string{c.Label, c.Term, c.Text} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Cover returns the URL of the cover, media:thumbnail is preferred over
+// itunes:image.
+func (m FeedMedia) Cover() string {
+	for _, t := range slices.Concat(m.Thumbnails, m.GroupThumbnails) {
+		if t.URL != "" {
+			return t.URL
+		}
+	}
+	return m.ItunesImage.Href
+}
+
+// NewFeedAPI creates a new instance of FeedAPI with a configured HTTP client.
+// It fetches the feed from feedURL, each request is limited to the given
+// timeout. When category is empty, the category of each book comes from the
+// feed categories.
+func NewFeedAPI(feedURL, category string, timeout time.Duration) *FeedAPI {
+	client := http.Client{
+		Timeout: timeout,
+	}
+
+	return &FeedAPI{
+		httpClient: &client,
+		feedURL:    feedURL,
+		category:   category,
+	}
+}
+
+// GetBooks fetches the feed and returns a book for every entry with a PDF
+// enclosure, in the order of the feed. Other entries are ignored.
+func (f *FeedAPI) GetBooks(ctx context.Context) ([]entities.Book, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("Cannot close response body", slog.Any("error", err))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: f.feedURL, StatusCode: resp.StatusCode}
+	}
+
+	var feed FeedXML
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(f.feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch feed.XMLName.Local {
+	case "rss":
+		return f.rssBooks(feed.Channel, base), nil
+	case "feed":
+		return f.atomBooks(feed, base), nil
+	default:
+		return nil, fmt.Errorf("unknown feed format %q", feed.XMLName.Local)
+	}
+}
+
+func (f *FeedAPI) rssBooks(channel FeedChannel, base *url.URL) []entities.Book {
+	books := make([]entities.Book, 0, len(channel.Items))
+	for _, item := range channel.Items {
+		var link string
+		for _, e := range item.Enclosures {
+			if isPDF(e.URL, e.Type) {
+				link = e.URL
+				break
+			}
+		}
+		if link == "" {
+			continue
+		}
+
+		description := item.Description
+		if description == "" {
+			description = item.Summary
+		}
+		cover := item.Cover()
+		if cover == "" {
+			cover = channel.ItunesImage.Href
+		}
+
+		books = append(books, f.book(base, item.Title, description, cover, link,
+			f.categoryOf(channel.Title, item.Categories, channel.Categories)))
+	}
+	return books
+}
+
+func (f *FeedAPI) atomBooks(feed FeedXML, base *url.URL) []entities.Book {
+	books := make([]entities.Book, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "enclosure" && isPDF(l.Href, l.Type) {
+				link = l.Href
+				break
+			}
+		}
+		if link == "" {
+			continue
+		}
+
+		description := entry.Summary
+		if description == "" {
+			description = entry.Content
+		}
+
+		books = append(books, f.book(base, entry.Title, description, entry.Cover(), link,
+			f.categoryOf(feed.Title, entry.Categories, feed.Categories)))
+	}
+	return books
+}
+
+func (f *FeedAPI) book(base *url.URL, title, description, cover, link, category string) entities.Book {
+	link = resolveURL(base, link)
+	return entities.Book{
+		Title:        plainText(title),
+		Description:  plainText(description),
+		Cover:        resolveURL(base, cover),
+		Link:         link,
+		Category:     category,
+		File:         entities.FileName(link),
+		Availability: entities.AvailabilityAvailable,
+	}
+}
+
+// categoryOf returns the configured category, or the first category of the
+// entry, or the first category of the feed, or the title of the feed.
+func (f *FeedAPI) categoryOf(feedTitle string, entry, feed []FeedCategory) string {
+	if f.category != "" {
+		return f.category
+	}
+	for _, c := range slices.Concat(entry, feed) {
+		if name := c.Name(); name != "" {
+			return name
+		}
+	}
+	return plainText(feedTitle)
+}
+
+// isPDF reports whether the enclosure is a PDF, from its media type or, when
+// the type is missing or generic, from the extension of its URL.
+func isPDF(link, mediaType string) bool {
+	if link == "" {
+		return false
+	}
+	if t, _, err := mime.ParseMediaType(mediaType); err == nil && t != "application/octet-stream" {
+		return t == "application/pdf"
+	}
+	u, err := url.Parse(link)
+	return err == nil && strings.EqualFold(path.Ext(u.Path), ".pdf")
+}
+
+// resolveURL resolves a link relative to the URL of the feed.
+func resolveURL(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(u).String()
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// plainText removes the markup feeds often use in titles and descriptions.
+func plainText(s string) string {
+	s = htmlTag.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(whitespace.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,135 @@
+package adapters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFeedServer serves the recorded feed file at /feeds/{name}.
+func newFeedServer(t *testing.T, name, contentType string) *httptest.Server {
+	t.Helper()
+	content, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feeds/"+name {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestFeedAPI(server *httptest.Server, feedURL, category string) *FeedAPI {
+	return &FeedAPI{
+		httpClient: server.Client(),
+		feedURL:    feedURL,
+		category:   category,
+	}
+}
+
+func TestFeedGetBooksRSS(t *testing.T) {
+	server := newFeedServer(t, "feed.rss", "application/rss+xml")
+	subject := newTestFeedAPI(server, server.URL+"/feeds/feed.rss", "")
+
+	result, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, []entities.Book{
+		{
+			Title:        "HackSpace 80",
+			Description:  "Build a robot arm & more",
+			Cover:        "http://localhost/hackspace/80.jpg",
+			Link:         "http://localhost/hackspace/HS80.pdf",
+			Category:     "HackSpace",
+			File:         "HS80.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "HackSpace 79",
+			Description:  "Wearables special",
+			Cover:        "http://localhost/hackspace/79.jpg",
+			Link:         server.URL + "/hackspace/HS79.pdf",
+			Category:     "Wearables",
+			File:         "HS79.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "HackSpace 78",
+			Description:  "No cover for this one",
+			Cover:        "http://localhost/hackspace/78.jpg",
+			Link:         "http://localhost/hackspace/HS78.pdf",
+			Category:     "HackSpace",
+			File:         "HS78.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "HackSpace 77",
+			Description:  "Uses the channel image",
+			Cover:        "http://localhost/hackspace/channel.jpg",
+			Link:         "http://localhost/hackspace/HS77.PDF",
+			Category:     "HackSpace",
+			File:         "HS77.PDF",
+			Availability: entities.AvailabilityAvailable,
+		},
+	}, result)
+}
+
+func TestFeedGetBooksAtom(t *testing.T) {
+	server := newFeedServer(t, "feed.atom", "application/atom+xml")
+	subject := newTestFeedAPI(server, server.URL+"/feeds/feed.atom", "")
+
+	result, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, []entities.Book{
+		{
+			Title:        "Wireframe 70",
+			Description:  "Retro games",
+			Cover:        "http://localhost/wireframe/70.jpg",
+			Link:         server.URL + "/feeds/pdf/Wireframe70.pdf",
+			Category:     "Wireframe",
+			File:         "Wireframe70.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+		{
+			Title:        "Wireframe 69",
+			Description:  "Level design",
+			Link:         "http://localhost/wireframe/pdf/Wireframe69.pdf",
+			Category:     "guides",
+			File:         "Wireframe69.pdf",
+			Availability: entities.AvailabilityAvailable,
+		},
+	}, result)
+}
+
+func TestFeedGetBooksConfiguredCategory(t *testing.T) {
+	server := newFeedServer(t, "feed.rss", "application/rss+xml")
+	subject := newTestFeedAPI(server, server.URL+"/feeds/feed.rss", "Magazines")
+
+	result, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	require.Len(t, result, 4)
+	for _, b := range result {
+		assert.Equal(t, "Magazines", b.Category, b.Title)
+	}
+}
+
+func TestFeedGetBooksErrors(t *testing.T) {
+	server := newFeedServer(t, "bookshelf.xml", "application/xml")
+
+	_, err := newTestFeedAPI(server, server.URL+"/feeds/missing.rss", "").GetBooks(t.Context())
+	assert.ErrorContains(t, err, "404")
+
+	_, err = newTestFeedAPI(server, server.URL+"/feeds/bookshelf.xml", "").GetBooks(t.Context())
+	assert.ErrorContains(t, err, "unknown feed format")
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issueMetadata is the structured information found in the title and the
+// file name of a MagPi publication, the zero values are unknown.
+type issueMetadata struct {
+	Issue   int
+	Year    int
+	Month   time.Month
+	Edition int
+}
+
+var (
+	// issueFilePattern matches the file names of the issues, such as
+	// MagPi150.pdf or RPOM160-1.pdf since the magazine was renamed to the
+	// Raspberry Pi Official Magazine. The suffix is a revision of the file.
+	issueFilePattern = regexp.MustCompile(`(?i)^(?:the[ _-]?)?(?:magpi|rpom)[ _-]?0*(\d{1,3})(?:[ _-]\d+)?$`)
+	// issueTitlePattern matches the issue number in titles such as
+	// The MagPi 150, The MagPi issue 150, MagPi #150 or Issue 150.
+	issueTitlePattern = regexp.MustCompile(`(?i)\b(?:magpi|magazine|mag|issue)\s*(?:issue\s*)?(?:#|no\.?\s*)?0*(\d{1,3})\b`)
+	// datePattern matches a month followed by a year, such as February 2025
+	// or Feb. 2025. The full names come first so they are not cut short.
+	datePattern = regexp.MustCompile(`(?i)\b(january|february|march|april|may|june|july|august|september|october|november|december|jan|feb|mar|apr|jun|jul|aug|sept|sep|oct|nov|dec)\.?,?\s+((?:19|20)\d{2})\b`)
+	yearPattern = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+	// editionPattern matches editions such as 5th edition, Second Edition
+	// or edition 2.
+	editionPattern = regexp.MustCompile(`(?i)\b(?:(\d{1,2})(?:st|nd|rd|th)|(first|second|third|fourth|fifth|sixth|seventh|eighth|ninth|tenth))\s+edition\b|\bedition\s+(\d{1,2})\b`)
+)
+
+var ordinals = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5,
+	"sixth": 6, "seventh": 7, "eighth": 8, "ninth": 9, "tenth": 10,
+}
+
+// parseIssueMetadata extracts the issue number, the publication date and
+// the edition from the title and the file name of a publication.
+// The file name is preferred for the issue number, upstream titles are
+// written by hand and vary much more.
+func parseIssueMetadata(title, file string) issueMetadata {
+	var meta issueMetadata
+	stem := strings.TrimSuffix(file, path.Ext(file))
+	// underscores are word characters, they would hide the years of names
+	// such as Book_of_Making_2026
+	words := strings.NewReplacer("_", " ", "-", " ").Replace(stem)
+
+	if m := issueFilePattern.FindStringSubmatch(stem); m != nil {
+		meta.Issue, _ = strconv.Atoi(m[1])
+	} else if m := issueTitlePattern.FindStringSubmatch(title); m != nil {
+		meta.Issue, _ = strconv.Atoi(m[1])
+	}
+
+	for _, text := range []string{title, words} {
+		if m := datePattern.FindStringSubmatch(text); m != nil {
+			meta.Year, _ = strconv.Atoi(m[2])
+			meta.Month = parseMonth(m[1])
+			break
+		}
+		if m := yearPattern.FindStringSubmatch(text); m != nil {
+			meta.Year, _ = strconv.Atoi(m[1])
+			break
+		}
+	}
+
+	for _, text := range []string{title, words} {
+		if m := editionPattern.FindStringSubmatch(text); m != nil {
+			switch {
+			case m[1] != "":
+				meta.Edition, _ = strconv.Atoi(m[1])
+			case m[2] != "":
+				meta.Edition = ordinals[strings.ToLower(m[2])]
+			default:
+				meta.Edition, _ = strconv.Atoi(m[3])
+			}
+			break
+		}
+	}
+
+	return meta
+}
+
+// parseMonth returns the month of a full or abbreviated English month name.
+func parseMonth(name string) time.Month {
+	prefix := strings.ToLower(name)[:3]
+	for m := time.January; m <= time.December; m++ {
+		if strings.ToLower(m.String()[:3]) == prefix {
+			return m
+		}
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIssueMetadata(t *testing.T) {
+	tests := []struct {
+		title    string
+		file     string
+		expected issueMetadata
+	}{
+		{title: "The MagPi 150", file: "MagPi150.pdf", expected: issueMetadata{Issue: 150}},
+		{title: "The MagPi issue 75", expected: issueMetadata{Issue: 75}},
+		{title: "MagPi #150 – February 2025", expected: issueMetadata{Issue: 150, Year: 2025, Month: time.February}},
+		{title: "The MagPi Issue 75 - November 2018", file: "MagPi75.pdf", expected: issueMetadata{Issue: 75, Year: 2018, Month: time.November}},
+		{title: "Issue 001", expected: issueMetadata{Issue: 1}},
+		{title: "MagPi No. 12, Sept. 2013", expected: issueMetadata{Issue: 12, Year: 2013, Month: time.September}},
+		{title: "Raspberry Pi Official Magazine 160 (December 2025)", file: "RPOM160-1.pdf", expected: issueMetadata{Issue: 160, Year: 2025, Month: time.December}},
+		{title: "Raspberry Pi Official Magazine", file: "RPOM_161.pdf", expected: issueMetadata{Issue: 161}},
+		{title: "A new look for the magazine", file: "The_MagPi_01.pdf", expected: issueMetadata{Issue: 1}},
+		{title: "The MagPi 2025 Annual", expected: issueMetadata{Year: 2025}},
+		{title: "Coming soon", file: "Book_of_Making_2026.pdf", expected: issueMetadata{Year: 2026}},
+		{title: "The Official Raspberry Pi Beginner's Guide 5th Edition", expected: issueMetadata{Edition: 5}},
+		{title: "Learn to Code with Scratch, Second Edition", expected: issueMetadata{Edition: 2}},
+		{title: "Retro Gaming", file: "Retro_Gaming_Edition_3.pdf", expected: issueMetadata{Edition: 3}},
+		{title: "Code the Classics Volume 2", file: "Code_the_Classics_2.pdf", expected: issueMetadata{}},
+		{title: "Get started with Raspberry Pi Pico 2", expected: issueMetadata{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseIssueMetadata(tt.title, tt.file))
+		})
+	}
+}
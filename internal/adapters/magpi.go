@@ -5,15 +5,28 @@ import (
 	"encoding/xml"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
 )
 
+// The categories of the items of the MagPi bookshelf.
+const (
+	magPiCategory = "MagPI"
+	bookCategory  = "Book"
+)
+
 // MagPiAPI is an adapter for fetching MagPi books and magazines.
+// It remembers the validators of the last bookshelf XML, so an unchanged
+// bookshelf is neither downloaded nor decoded again.
 type MagPiAPI struct {
 	httpClient        *http.Client
 	magPiBookShelfURL string
+
+	mu           sync.Mutex
+	etag         string
+	lastModified string
 }
 
 // BookshelfXML represents the structure of the MagPi bookshelf XML response.
@@ -39,37 +52,72 @@ func (i *BookshelfItem) IsLocked() bool {
 }
 
 // ToBookEntity converts a BookshelfItem to an entities.Book.
+// The issue number of the magazines, the edition of the books and their
+// publication date are parsed from the title and the file name.
 func (i *BookshelfItem) ToBookEntity() entities.Book {
-	return entities.Book{
-		Title:       i.Title,
-		Description: i.Description,
-		Cover:       i.Cover,
-		Link:        i.PDF,
-		Category:    i.Category,
+	availability := entities.AvailabilityAvailable
+	if i.IsLocked() {
+		availability = entities.AvailabilityLocked
+	}
+
+	meta := parseIssueMetadata(i.Title, i.File)
+	book := entities.Book{
+		Title:        i.Title,
+		Description:  i.Description,
+		Cover:        i.Cover,
+		Link:         i.PDF,
+		Category:     i.Category,
+		File:         i.File,
+		Availability: availability,
+		Year:         meta.Year,
+		Month:        meta.Month,
 	}
+	switch i.Category {
+	case magPiCategory:
+		book.Issue = meta.Issue
+	case bookCategory:
+		book.Edition = meta.Edition
+	}
+	return book
 }
 
 // NewMagPiAPI creates a new instance of MagPiAPI with a configured HTTP client.
-func NewMagPiAPI() *MagPiAPI {
+// It fetches the bookshelf XML from bookshelfURL, each request is limited to
+// the given timeout.
+func NewMagPiAPI(bookshelfURL string, timeout time.Duration) *MagPiAPI {
 	client := http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: timeout,
 	}
 
 	return &MagPiAPI{
 		httpClient:        &client,
-		magPiBookShelfURL: "https://magpi.raspberrypi.com/bookshelf.xml",
+		magPiBookShelfURL: bookshelfURL,
 	}
 }
 
 // GetBooks fetches the list of MagPi books and magazines from the MagPi API.
 // It returns a slice of Book entities or an error if the operation fails.
-// The function uses concurrency to process magazines and books in parallel.
+// When the bookshelf did not change since the previous successful call,
+// entities.ErrUnchanged is returned. Unexpected statuses are returned as a
+// *StatusError.
+// The magazines are returned first, then the books, both in the order of
+// the bookshelf, so the same bookshelf always gives the same catalog.
 func (m *MagPiAPI) GetBooks(ctx context.Context) ([]entities.Book, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.magPiBookShelfURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	m.mu.Lock()
+	conditional := m.etag != "" || m.lastModified != ""
+	if m.etag != "" {
+		req.Header.Set("If-None-Match", m.etag)
+	}
+	if m.lastModified != "" {
+		req.Header.Set("If-Modified-Since", m.lastModified)
+	}
+	m.mu.Unlock()
+
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -81,47 +129,40 @@ func (m *MagPiAPI) GetBooks(ctx context.Context) ([]entities.Book, error) {
 		}
 	}()
 
+	switch {
+	case resp.StatusCode == http.StatusNotModified && conditional:
+		return nil, entities.ErrUnchanged
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, &StatusError{URL: m.magPiBookShelfURL, StatusCode: resp.StatusCode}
+	}
+
 	var magPiXML BookshelfXML
 	err = xml.NewDecoder(resp.Body).Decode(&magPiXML)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]entities.Book, 0, len(magPiXML.Books)+len(magPiXML.MagPi))
-	magzCh := make(chan entities.Book, 1)
-	bookCh := make(chan entities.Book, 1)
-
-	go func() {
-		for _, item := range magPiXML.MagPi {
-			item.Category = "MagPI"
-			magzCh <- item.ToBookEntity()
-		}
-		close(magzCh)
-	}()
-	go func() {
-		for _, item := range magPiXML.Books {
-			item.Category = "Book"
-			bookCh <- item.ToBookEntity()
-		}
-		close(bookCh)
-	}()
-
-	for count := 0; count < 2; {
-		select {
-		case v, ok := <-magzCh:
-			if !ok {
-				magzCh = nil
-				count++
-				continue
-			}
-			result = append(result, v)
-		case v, ok := <-bookCh:
-			if !ok {
-				bookCh = nil
-				count++
-				continue
-			}
-			result = append(result, v)
+	// the validators are only kept once the bookshelf is decoded, otherwise
+	// a broken response would be considered unchanged forever
+	m.mu.Lock()
+	m.etag = resp.Header.Get("ETag")
+	m.lastModified = resp.Header.Get("Last-Modified")
+	m.mu.Unlock()
+
+	// the magazines come first, then the books, each in the order of the
+	// bookshelf, the sections do not say which category they hold
+	sections := []struct {
+		category string
+		items    []BookshelfItem
+	}{
+		{category: magPiCategory, items: magPiXML.MagPi},
+		{category: bookCategory, items: magPiXML.Books},
+	}
+	result := make([]entities.Book, 0, len(magPiXML.MagPi)+len(magPiXML.Books))
+	for _, section := range sections {
+		for _, item := range section.items {
+			item.Category = section.category
+			result = append(result, item.ToBookEntity())
 		}
 	}
 
@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -30,45 +31,157 @@ func TestGetBooks(t *testing.T) {
 	result, err := subject.GetBooks(t.Context())
 	require.Nil(t, err, "get books returned error: %v", err)
 	require.NotEmpty(t, result, "result cannot be empty")
-	require.Equal(t, 5, len(result), "should have 3 items")
+	require.Equal(t, 5, len(result), "should have 5 items")
 
 	expectedItems := []entities.Book{
 		{
-			Title:       "MagPI Locked Mag 1",
-			Description: "Description for MagPi Mag 1",
-			Cover:       "http://localhost/covers/1",
-			Link:        "",
-			Category:    "MagPI",
+			Title:        "MagPI Locked Mag 1",
+			Description:  "Description for MagPi Mag 1",
+			Cover:        "http://localhost/covers/1",
+			Link:         "",
+			Category:     "MagPI",
+			File:         "RPOM160-1.pdf",
+			Availability: entities.AvailabilityLocked,
+			Issue:        160,
 		},
 		{
-			Title:       "MagPI Available Mag 2",
-			Description: "Description for the MagPI Available Mag 2",
-			Cover:       "http://localhost/covers/2",
-			Link:        "http://localhost/magpi/2",
-			Category:    "MagPI",
+			Title:        "MagPI Available Mag 2",
+			Description:  "Description for the MagPI Available Mag 2",
+			Cover:        "http://localhost/covers/2",
+			Link:         "http://localhost/magpi/2",
+			Category:     "MagPI",
+			Availability: entities.AvailabilityAvailable,
+			Issue:        2,
 		},
 		{
-			Title:       "MagPI Available Mag 3",
-			Description: "Description for the MagPI Available Mag 3",
-			Cover:       "http://localhost/covers/3",
-			Link:        "http://localhost/magpi/3",
-			Category:    "MagPI",
+			Title:        "MagPI Available Mag 3",
+			Description:  "Description for the MagPI Available Mag 3",
+			Cover:        "http://localhost/covers/3",
+			Link:         "http://localhost/magpi/3",
+			Category:     "MagPI",
+			Availability: entities.AvailabilityAvailable,
+			Issue:        3,
 		},
 		{
-			Title:       "Some non available book yet",
-			Description: "This is a forbidden book",
-			Cover:       "http://localhost/covers/4",
-			Link:        "",
-			Category:    "Book",
+			Title:        "Some non available book yet",
+			Description:  "This is a forbidden book",
+			Cover:        "http://localhost/covers/4",
+			Link:         "",
+			Category:     "Book",
+			File:         "Book_of_Making_2026.pdf",
+			Availability: entities.AvailabilityLocked,
+			Year:         2026,
 		},
 		{
-			Title:       "Available Book 1",
-			Description: "Description for the Available Book 1",
-			Cover:       "http://localhost/covers/book1",
-			Link:        "http://localhost/book/1",
-			Category:    "Book",
+			Title:        "Available Book 1",
+			Description:  "Description for the Available Book 1",
+			Cover:        "http://localhost/covers/book1",
+			Link:         "http://localhost/book/1",
+			Category:     "Book",
+			Availability: entities.AvailabilityAvailable,
 		},
 	}
 
-	assert.ElementsMatch(t, expectedItems, result, "result should match")
+	assert.Equal(t, expectedItems, result, "the magazines come first, then the books, in the order of the bookshelf")
+}
+
+func TestGetBooksConditionalRequests(t *testing.T) {
+	xmlContent, err := os.ReadFile("testdata/bookshelf.xml")
+	require.NoError(t, err)
+
+	const (
+		etag         = `"v1"`
+		lastModified = "Wed, 01 Jan 2025 00:00:00 GMT"
+	)
+	var requests []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write(xmlContent)
+	}))
+	defer server.Close()
+
+	subject := MagPiAPI{
+		httpClient:        server.Client(),
+		magPiBookShelfURL: server.URL,
+	}
+
+	result, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, result, 5)
+
+	result, err = subject.GetBooks(t.Context())
+	assert.ErrorIs(t, err, entities.ErrUnchanged)
+	assert.Nil(t, result)
+
+	require.Len(t, requests, 2)
+	assert.Empty(t, requests[0].Get("If-None-Match"))
+	assert.Equal(t, etag, requests[1].Get("If-None-Match"))
+	assert.Equal(t, lastModified, requests[1].Get("If-Modified-Since"))
+}
+
+func TestGetBooksUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"error"`)
+		http.Error(w, "<html>maintenance</html>", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	subject := MagPiAPI{
+		httpClient:        server.Client(),
+		magPiBookShelfURL: server.URL,
+	}
+
+	_, err := subject.GetBooks(t.Context())
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr), "got %v", err)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	assert.Equal(t, server.URL, statusErr.URL)
+	assert.Empty(t, subject.etag, "validators of an error response are not kept")
+}
+
+func TestGetBooksOrder(t *testing.T) {
+	// the books come before the magazines in this bookshelf
+	xmlContent := `<PUBS>
+  <BOOKS>
+    <ITEM><TITLE>Book B</TITLE><PDF>http://localhost/b</PDF></ITEM>
+    <ITEM><TITLE>Book A</TITLE><PDF>http://localhost/a</PDF></ITEM>
+  </BOOKS>
+  <MAGPI>
+    <ITEM><TITLE>The MagPi 151</TITLE></ITEM>
+    <ITEM><TITLE>The MagPi 150</TITLE><PDF>http://localhost/150</PDF></ITEM>
+    <ITEM><TITLE>The MagPi 149</TITLE><PDF>http://localhost/149</PDF></ITEM>
+  </MAGPI>
+</PUBS>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(xmlContent))
+	}))
+	defer server.Close()
+
+	subject := MagPiAPI{
+		httpClient:        server.Client(),
+		magPiBookShelfURL: server.URL,
+	}
+
+	for range 10 {
+		result, err := subject.GetBooks(t.Context())
+		require.NoError(t, err)
+
+		var got []string
+		for _, b := range result {
+			got = append(got, b.Category+"/"+b.Title)
+		}
+		assert.Equal(t, []string{
+			"MagPI/The MagPi 151",
+			"MagPI/The MagPi 150",
+			"MagPI/The MagPi 149",
+			"Book/Book B",
+			"Book/Book A",
+		}, got)
+	}
 }
@@ -0,0 +1,380 @@
+package adapters
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfInfo holds the fields of the document information dictionary of a PDF.
+type pdfInfo struct {
+	Title   string
+	Subject string
+}
+
+const (
+	// pdfTailSize is the size of the end of the file searched for the
+	// trailer, which references the information dictionary.
+	pdfTailSize = 16 << 10
+	// pdfWindowSize is the size read at the offset of a cross-reference
+	// section or of an object.
+	pdfWindowSize = 16 << 10
+	// pdfScanChunkSize is the size of the chunks of a file scanned for an
+	// object, when it is not found by the cross-reference table.
+	pdfScanChunkSize = 64 << 10
+	// pdfMaxXrefSections bounds the chain of cross-reference sections left by
+	// incremental updates.
+	pdfMaxXrefSections = 64
+	pdfWhitespace      = " \t\r\n\f\x00"
+)
+
+var (
+	errNoPDFInfo = errors.New("no document information dictionary")
+
+	// pdfInfoRef matches the reference to the information dictionary in the
+	// trailer, or in the dictionary of a cross-reference stream.
+	pdfInfoRef = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	// pdfStartXref matches the offset of the last cross-reference section.
+	pdfStartXref = regexp.MustCompile(`startxref\s+(\d+)`)
+	// pdfXrefSubsection matches the first object number and the number of
+	// entries of a subsection of a cross-reference table.
+	pdfXrefSubsection = regexp.MustCompile(`^\s*(\d+)\s+(\d+)[ \t]*(?:\r\n|\r|\n)`)
+	// pdfPrev matches the offset of the previous cross-reference section in
+	// a trailer.
+	pdfPrev = regexp.MustCompile(`/Prev\s+(\d+)`)
+)
+
+// readPDFInfo extracts the title and subject of a PDF from its document
+// information dictionary, without reading the whole file: the reference to
+// the dictionary is read from the trailer at the end of the file, and the
+// dictionary itself from the offset the cross-reference table gives.
+// It is not a PDF parser, it only understands information dictionaries
+// stored as plain objects, which is what most PDF writers produce. Values
+// inside compressed object streams are not found.
+func readPDFInfo(r io.ReaderAt, size int64) (pdfInfo, error) {
+	tail, err := readPDFWindow(r, max(size-pdfTailSize, 0), pdfTailSize)
+	if err != nil {
+		return pdfInfo{}, err
+	}
+
+	xref := int64(-1)
+	if m := lastSubmatch(pdfStartXref, tail); m != nil {
+		xref, _ = strconv.ParseInt(string(m[1]), 10, 64)
+	}
+	// incremental updates append a new trailer, the last one wins
+	ref := lastSubmatch(pdfInfoRef, tail)
+	if ref == nil && xref >= 0 {
+		// the dictionary of a cross-reference stream comes before its data
+		section, err := readPDFWindow(r, xref, pdfWindowSize)
+		if err != nil {
+			return pdfInfo{}, err
+		}
+		if end := bytes.Index(section, []byte("stream")); end >= 0 {
+			section = section[:end]
+		}
+		ref = lastSubmatch(pdfInfoRef, section)
+	}
+	if ref == nil {
+		return pdfInfo{}, errNoPDFInfo
+	}
+	num, err := strconv.ParseInt(string(ref[1]), 10, 64)
+	if err != nil {
+		return pdfInfo{}, errNoPDFInfo
+	}
+	gen, err := strconv.ParseInt(string(ref[2]), 10, 64)
+	if err != nil {
+		return pdfInfo{}, errNoPDFInfo
+	}
+
+	offset, ok, err := pdfObjectOffset(r, xref, num, gen)
+	if err != nil {
+		return pdfInfo{}, err
+	}
+	if ok {
+		info, found, err := readPDFInfoObject(r, offset, num, gen)
+		if err != nil || found {
+			return info, err
+		}
+	}
+
+	// cross-reference streams are not decoded, and some writers get the
+	// offsets wrong, the object is looked for instead
+	offset, ok, err = scanPDFObject(r, size, num, gen)
+	if err != nil || !ok {
+		return pdfInfo{}, cmp.Or(err, errNoPDFInfo)
+	}
+	info, found, err := readPDFInfoObject(r, offset, num, gen)
+	if err == nil && !found {
+		err = errNoPDFInfo
+	}
+	return info, err
+}
+
+// pdfObjectOffset returns the offset of the object in the cross-reference
+// table starting at xref, following the previous sections of the table.
+// It reports false when the object is not found, in particular when the
+// cross-references are a stream.
+func pdfObjectOffset(r io.ReaderAt, xref, num, gen int64) (int64, bool, error) {
+	for range pdfMaxXrefSections {
+		if xref < 0 {
+			return 0, false, nil
+		}
+		section, err := readPDFWindow(r, xref, pdfWindowSize)
+		if err != nil {
+			return 0, false, err
+		}
+		rest, ok := bytes.CutPrefix(bytes.TrimLeft(section, pdfWhitespace), []byte("xref"))
+		if !ok {
+			return 0, false, nil
+		}
+		pos := xref + int64(len(section)-len(rest))
+
+		for {
+			header, err := readPDFWindow(r, pos, 64)
+			if err != nil {
+				return 0, false, err
+			}
+			m := pdfXrefSubsection.FindSubmatch(header)
+			if m == nil {
+				// the trailer follows the last subsection
+				break
+			}
+			first, _ := strconv.ParseInt(string(m[1]), 10, 64)
+			count, _ := strconv.ParseInt(string(m[2]), 10, 64)
+			pos += int64(len(m[0]))
+			if num < first || num >= first+count {
+				// every entry is 20 bytes long
+				pos += 20 * count
+				continue
+			}
+
+			entry, err := readPDFWindow(r, pos+20*(num-first), 20)
+			if err != nil {
+				return 0, false, err
+			}
+			fields := bytes.Fields(entry)
+			if len(fields) < 3 || string(fields[2]) != "n" {
+				// the object was deleted
+				return 0, false, nil
+			}
+			offset, err1 := strconv.ParseInt(string(fields[0]), 10, 64)
+			entryGen, err2 := strconv.ParseInt(string(fields[1]), 10, 64)
+			if err1 != nil || err2 != nil || entryGen != gen {
+				return 0, false, nil
+			}
+			return offset, true, nil
+		}
+
+		trailer, err := readPDFWindow(r, pos, pdfWindowSize)
+		if err != nil {
+			return 0, false, err
+		}
+		if end := bytes.Index(trailer, []byte("startxref")); end >= 0 {
+			trailer = trailer[:end]
+		}
+		m := pdfPrev.FindSubmatch(trailer)
+		if m == nil {
+			return 0, false, nil
+		}
+		xref, _ = strconv.ParseInt(string(m[1]), 10, 64)
+	}
+	return 0, false, nil
+}
+
+// scanPDFObject returns the offset of the last definition of the object in
+// the file, which is read a chunk at a time.
+func scanPDFObject(r io.ReaderAt, size, num, gen int64) (int64, bool, error) {
+	pattern := regexp.MustCompile(fmt.Sprintf(`\s%d\s+%d\s+obj\b`, num, gen))
+
+	found := int64(-1)
+	// the start of the file counts as whitespace
+	carry := []byte{'\n'}
+	for offset := int64(0); offset < size; offset += pdfScanChunkSize {
+		chunk, err := readPDFWindow(r, offset, pdfScanChunkSize)
+		if err != nil {
+			return 0, false, err
+		}
+		buf := slices.Concat(carry, chunk)
+		if locs := pattern.FindAllIndex(buf, -1); len(locs) > 0 {
+			// the match starts with the whitespace before the object number
+			found = offset - int64(len(carry)) + int64(locs[len(locs)-1][0]) + 1
+		}
+		// an object header cut between two chunks is found in the next one
+		carry = buf[max(len(buf)-64, 0):]
+	}
+	return found, found >= 0, nil
+}
+
+// readPDFInfoObject reads the information dictionary stored as the object
+// at offset. It reports false when the object is not at offset.
+func readPDFInfoObject(r io.ReaderAt, offset, num, gen int64) (pdfInfo, bool, error) {
+	obj, err := readPDFWindow(r, offset, pdfWindowSize)
+	if err != nil {
+		return pdfInfo{}, false, err
+	}
+	header := regexp.MustCompile(fmt.Sprintf(`^\s*%d\s+%d\s+obj\b`, num, gen))
+	loc := header.FindIndex(obj)
+	if loc == nil {
+		return pdfInfo{}, false, nil
+	}
+	obj = obj[loc[1]:]
+	if end := bytes.Index(obj, []byte("endobj")); end >= 0 {
+		obj = obj[:end]
+	}
+
+	return pdfInfo{
+		Title:   pdfDictString(obj, "/Title"),
+		Subject: pdfDictString(obj, "/Subject"),
+	}, true, nil
+}
+
+// readPDFWindow reads up to n bytes at offset, less at the end of the file.
+func readPDFWindow(r io.ReaderAt, offset int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := r.ReadAt(buf, offset)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return buf[:read], err
+}
+
+// lastSubmatch returns the submatches of the last match of re in data.
+func lastSubmatch(re *regexp.Regexp, data []byte) [][]byte {
+	matches := re.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[len(matches)-1]
+}
+
+// pdfDictString returns the text string stored under key in the dictionary.
+func pdfDictString(dict []byte, key string) string {
+	i := bytes.Index(dict, []byte(key))
+	if i < 0 {
+		return ""
+	}
+	value := bytes.TrimLeft(dict[i+len(key):], " \t\r\n")
+	if len(value) == 0 {
+		return ""
+	}
+
+	var raw []byte
+	switch value[0] {
+	case '(':
+		raw = pdfLiteralString(value[1:])
+	case '<':
+		end := bytes.IndexByte(value, '>')
+		if end < 0 {
+			return ""
+		}
+		raw = pdfHexString(value[1:end])
+	default:
+		return ""
+	}
+	return strings.TrimSpace(pdfTextString(raw))
+}
+
+// pdfLiteralString decodes a literal string, s starts after the opening
+// parenthesis.
+func pdfLiteralString(s []byte) []byte {
+	var result []byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				result = append(result, '\n')
+			case 'r':
+				result = append(result, '\r')
+			case 't':
+				result = append(result, '\t')
+			case 'b':
+				result = append(result, '\b')
+			case 'f':
+				result = append(result, '\f')
+			case '\r', '\n':
+				// line continuation
+				if e == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					end := i + 1
+					for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+						end++
+					}
+					n, _ := strconv.ParseUint(string(s[i:end]), 8, 8)
+					result = append(result, byte(n))
+					i = end - 1
+				} else {
+					result = append(result, e)
+				}
+			}
+		case c == '(':
+			depth++
+			result = append(result, c)
+		case c == ')':
+			if depth == 0 {
+				return result
+			}
+			depth--
+			result = append(result, c)
+		default:
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// pdfHexString decodes a hexadecimal string, whitespace is ignored and a
+// missing final digit is zero.
+func pdfHexString(s []byte) []byte {
+	var digits []byte
+	for _, c := range s {
+		if strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	result := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		n, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		result = append(result, byte(n))
+	}
+	return result
+}
+
+// pdfTextString converts a PDF text string to UTF-8. Text strings are either
+// UTF-16BE with a byte order mark, UTF-8 with a byte order mark, or
+// PDFDocEncoding, which is close enough to Latin-1 for titles.
+func pdfTextString(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0xfe, 0xff}):
+		raw = raw[2:]
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(raw, []byte{0xef, 0xbb, 0xbf}):
+		return string(raw[3:])
+	default:
+		runes := make([]rune, 0, len(raw))
+		for _, b := range raw {
+			runes = append(runes, rune(b))
+		}
+		return string(runes)
+	}
+}
@@ -0,0 +1,166 @@
+package adapters
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPDFInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		pdf      string
+		expected pdfInfo
+	}{
+		{
+			name: "literal strings",
+			pdf: "%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n" +
+				"7 0 obj\n<< /Title (Training \\(part 1\\)) /Subject (Line one\\nline two) >>\nendobj\n" +
+				"trailer\n<< /Root 1 0 R /Info 7 0 R >>\n%%EOF\n",
+			expected: pdfInfo{Title: "Training (part 1)", Subject: "Line one\nline two"},
+		},
+		{
+			name: "UTF-16 hex string",
+			pdf: "%PDF-1.7\n3 0 obj\n<</Title <FEFF00430061006600E9>>>\nendobj\n" +
+				"trailer\n<</Info 3 0 R>>\n",
+			expected: pdfInfo{Title: "Café"},
+		},
+		{
+			name:     "octal escapes in PDFDocEncoding",
+			pdf:      "2 0 obj\n<< /Title (Caf\\351) >>\nendobj\ntrailer << /Info 2 0 R >>",
+			expected: pdfInfo{Title: "Café"},
+		},
+		{
+			name: "last trailer wins",
+			pdf: "12 0 obj\n<< /Title (Old) >>\nendobj\ntrailer << /Info 12 0 R >>\n" +
+				"13 0 obj\n<< /Title (New) >>\nendobj\ntrailer << /Info 13 0 R /Prev 100 >>\n",
+			expected: pdfInfo{Title: "New"},
+		},
+		{
+			name: "object scanned for across chunks",
+			pdf: "%PDF-1.5\n" + strings.Repeat("x", pdfScanChunkSize-12) + "\n5 0 obj\n<< /Title (Scanned) >>\nendobj\n" +
+				"trailer << /Info 5 0 R >>\n",
+			expected: pdfInfo{Title: "Scanned"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := readPDFInfo(strings.NewReader(tt.pdf), int64(len(tt.pdf)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, info)
+		})
+	}
+}
+
+func TestReadPDFInfoWithoutDictionary(t *testing.T) {
+	pdf := "%PDF-1.4\ntrailer << /Root 1 0 R >>\n"
+	_, err := readPDFInfo(strings.NewReader(pdf), int64(len(pdf)))
+	assert.ErrorIs(t, err, errNoPDFInfo)
+}
+
+// pdfRevision is the objects written by a PDF writer, or by an incremental
+// update, followed by their cross-reference section and trailer.
+type pdfRevision struct {
+	objects map[int]string
+	trailer string
+}
+
+// buildPDF writes the revisions of a PDF one after the other, each of them
+// pointing to the cross-reference section of the previous one.
+func buildPDF(revisions ...pdfRevision) []byte {
+	pdf := []byte("%PDF-1.4\n")
+	prev := -1
+	for _, rev := range revisions {
+		nums := slices.Sorted(maps.Keys(rev.objects))
+		offsets := map[int]int{}
+		for _, n := range nums {
+			offsets[n] = len(pdf)
+			pdf = fmt.Appendf(pdf, "%d 0 obj\n%s\nendobj\n", n, rev.objects[n])
+		}
+
+		xref := len(pdf)
+		pdf = append(pdf, "xref\n0 1\n0000000000 65535 f \n"...)
+		for _, n := range nums {
+			pdf = fmt.Appendf(pdf, "%d 1\n%010d 00000 n \n", n, offsets[n])
+		}
+		trailer := rev.trailer
+		if prev >= 0 {
+			trailer += fmt.Sprintf(" /Prev %d", prev)
+		}
+		pdf = fmt.Appendf(pdf, "trailer\n<< %s >>\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+		prev = xref
+	}
+	return pdf
+}
+
+// countingReader counts the bytes read from the PDF.
+type countingReader struct {
+	r    *bytes.Reader
+	read atomic.Int64
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func TestReadPDFInfoFollowsCrossReferences(t *testing.T) {
+	// the pages are large, the information dictionary is far from the end
+	pages := "<< /Length 1048576 >>\nstream\n" + strings.Repeat("x", 1<<20) + "\nendstream"
+	first := pdfRevision{
+		objects: map[int]string{
+			1:  "<< /Type /Catalog >>",
+			2:  "<< /Title (First) /Subject (Original) >>",
+			3:  pages,
+			12: "<< /Title (Not the information dictionary) >>",
+		},
+		trailer: "/Root 1 0 R /Info 2 0 R",
+	}
+
+	tests := []struct {
+		name     string
+		pdf      []byte
+		expected pdfInfo
+	}{
+		{
+			name:     "cross-reference table",
+			pdf:      buildPDF(first),
+			expected: pdfInfo{Title: "First", Subject: "Original"},
+		},
+		{
+			name: "dictionary in a previous section",
+			pdf: buildPDF(first, pdfRevision{
+				objects: map[int]string{1: "<< /Type /Catalog /Version /1.7 >>"},
+				trailer: "/Root 1 0 R /Info 2 0 R",
+			}),
+			expected: pdfInfo{Title: "First", Subject: "Original"},
+		},
+		{
+			name: "dictionary replaced by an incremental update",
+			pdf: buildPDF(first, pdfRevision{
+				objects: map[int]string{2: "<< /Title (Second) >>"},
+				trailer: "/Root 1 0 R /Info 2 0 R",
+			}),
+			expected: pdfInfo{Title: "Second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &countingReader{r: bytes.NewReader(tt.pdf)}
+			info, err := readPDFInfo(r, int64(len(tt.pdf)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, info)
+			assert.Less(t, r.read.Load(), int64(128<<10), "only the trailer and the dictionary are read")
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package bookshelf
+
+import (
+	"math"
+	"time"
+)
+
+// Backoff computes the delay before retrying a failed refresh. The delay
+// grows exponentially with the number of consecutive failures, is spread by
+// a random jitter so instances do not retry in lockstep, and never exceeds
+// Max.
+type Backoff struct {
+	// Initial is the delay after the first failure.
+	Initial time.Duration
+	// Max caps the delay.
+	Max time.Duration
+	// Multiplier is the growth of the delay after each failure.
+	Multiplier float64
+	// Jitter is the fraction of the delay randomly added or removed,
+	// between 0 and 1.
+	Jitter float64
+}
+
+// Delay returns the delay after the given number of consecutive failures,
+// starting at 1. random is a number in [0, 1) choosing the jitter.
+func (b Backoff) Delay(failures int, random float64) time.Duration {
+	delay := float64(b.Initial) * math.Pow(b.Multiplier, float64(max(failures-1, 0)))
+	delay = min(delay, float64(b.Max))
+	delay *= 1 + b.Jitter*(2*random-1)
+	return time.Duration(min(max(delay, 0), float64(b.Max)))
+}
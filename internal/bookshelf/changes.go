@@ -0,0 +1,188 @@
+package bookshelf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/atomicfile"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+const (
+	// changesFileName is the name of the change log file inside the data directory.
+	changesFileName = "changes.json"
+	// changesFileVersion is the current version of the change log file format.
+	changesFileVersion = 1
+	// maxChanges is the number of changes kept in the log, the oldest are dropped.
+	maxChanges = 500
+)
+
+// Diff compares two catalogs and returns the changes from previous to current,
+// all detected at the given time.
+// Books are matched by ID, the added and unlocked books come first in the
+// order of the current catalog, followed by the removed books in the order
+// of the previous one.
+func Diff(previous, current []entities.Book, at time.Time) []entities.Change {
+	before := make(map[string]entities.Book, len(previous))
+	for _, b := range previous {
+		before[b.ID] = b
+	}
+
+	var changes []entities.Change
+	seen := make(map[string]bool, len(current))
+	for _, b := range current {
+		seen[b.ID] = true
+		old, ok := before[b.ID]
+		switch {
+		case !ok:
+			changes = append(changes, entities.Change{Kind: entities.ChangeAdded, Book: b, At: at})
+		case old.Link == "" && b.Link != "":
+			changes = append(changes, entities.Change{Kind: entities.ChangeUnlocked, Book: b, At: at})
+		}
+	}
+	for _, b := range previous {
+		if !seen[b.ID] {
+			changes = append(changes, entities.Change{Kind: entities.ChangeRemoved, Book: b, At: at})
+		}
+	}
+	return changes
+}
+
+// changesFile is the on-disk representation of the change log.
+type changesFile struct {
+	Version int           `json:"version"`
+	Changes []changeEntry `json:"changes"`
+}
+
+type changeEntry struct {
+	Kind entities.ChangeKind `json:"kind"`
+	At   time.Time           `json:"at"`
+	Book catalogBook         `json:"book"`
+}
+
+// ChangeLog keeps the most recent changes of the catalog, and a copy of
+// them on disk so the feeds survive a restart.
+type ChangeLog struct {
+	path string
+
+	mu sync.RWMutex
+	// changes are sorted from the oldest to the newest.
+	changes []entities.Change
+}
+
+// NewChangeLog creates a new instance of ChangeLog that keeps its file in
+// the given data directory.
+func NewChangeLog(dataDir string) *ChangeLog {
+	return &ChangeLog{
+		path: filepath.Join(dataDir, changesFileName),
+	}
+}
+
+// Load reads the change log file, if present.
+// A missing file is not an error, the log is simply left empty.
+func (l *ChangeLog) Load(_ context.Context) error {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read change log: %w", err)
+	}
+
+	var file changesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cannot decode change log: %w", err)
+	}
+	if file.Version < 1 || file.Version > changesFileVersion {
+		return fmt.Errorf("unsupported change log version %d", file.Version)
+	}
+
+	changes := make([]entities.Change, 0, len(file.Changes))
+	for _, c := range file.Changes {
+		changes = append(changes, entities.Change{
+			Kind: c.Kind,
+			At:   c.At,
+			Book: c.Book.toBookEntity(),
+		})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes = changes
+	return nil
+}
+
+// Record appends the changes to the log and writes it to disk.
+// The changes are kept in memory even if they cannot be persisted.
+func (l *ChangeLog) Record(_ context.Context, changes []entities.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.changes = append(l.changes, changes...)
+	if len(l.changes) > maxChanges {
+		l.changes = slices.Clone(l.changes[len(l.changes)-maxChanges:])
+	}
+
+	if err := l.save(); err != nil {
+		return fmt.Errorf("cannot persist change log: %w", err)
+	}
+	return nil
+}
+
+// Recent returns up to limit changes, newest first, optionally filtered by
+// category. A limit of zero or less returns every change.
+func (l *ChangeLog) Recent(_ context.Context, category string, limit int) ([]entities.Change, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := []entities.Change{}
+	for i := len(l.changes) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		if c := l.changes[i]; category == "" || c.Book.Category == category {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// save atomically writes the change log to disk.
+// It must be called with the lock held.
+func (l *ChangeLog) save() error {
+	file := changesFile{
+		Version: changesFileVersion,
+		Changes: make([]changeEntry, 0, len(l.changes)),
+	}
+	for _, c := range l.changes {
+		file.Changes = append(file.Changes, changeEntry{
+			Kind: c.Kind,
+			At:   c.At,
+			Book: newCatalogBook(c.Book),
+		})
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(l.path, data)
+}
@@ -0,0 +1,110 @@
+package bookshelf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	previous := []entities.Book{
+		{ID: "kept", Link: "http://localhost/kept.pdf"},
+		{ID: "locked"},
+		{ID: "unlocked"},
+		{ID: "removed", Link: "http://localhost/removed.pdf"},
+	}
+	current := []entities.Book{
+		{ID: "added"},
+		{ID: "kept", Link: "http://localhost/kept.pdf"},
+		{ID: "locked"},
+		{ID: "unlocked", Link: "http://localhost/unlocked.pdf"},
+	}
+
+	assert.Equal(t, []entities.Change{
+		{Kind: entities.ChangeAdded, Book: current[0], At: at},
+		{Kind: entities.ChangeUnlocked, Book: current[3], At: at},
+		{Kind: entities.ChangeRemoved, Book: previous[3], At: at},
+	}, Diff(previous, current, at))
+	assert.Empty(t, Diff(current, current, at))
+}
+
+func TestChangeLogRecent(t *testing.T) {
+	log := NewChangeLog(t.TempDir())
+	at := time.Now().UTC()
+	require.NoError(t, log.Record(t.Context(), []entities.Change{
+		{Kind: entities.ChangeAdded, Book: entities.Book{ID: "1", Category: "MagPI"}, At: at},
+		{Kind: entities.ChangeAdded, Book: entities.Book{ID: "2", Category: "Book"}, At: at},
+	}))
+	require.NoError(t, log.Record(t.Context(), []entities.Change{
+		{Kind: entities.ChangeAdded, Book: entities.Book{ID: "3", Category: "MagPI"}, At: at},
+	}))
+
+	ids := func(changes []entities.Change) []string {
+		result := []string{}
+		for _, c := range changes {
+			result = append(result, c.Book.ID)
+		}
+		return result
+	}
+
+	all, err := log.Recent(t.Context(), "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2", "1"}, ids(all), "newest first")
+
+	magpi, err := log.Recent(t.Context(), "MagPI", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "1"}, ids(magpi))
+
+	limited, err := log.Recent(t.Context(), "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2"}, ids(limited))
+}
+
+func TestChangeLogKeepsTheNewestChanges(t *testing.T) {
+	log := NewChangeLog(t.TempDir())
+	for i := range maxChanges + 10 {
+		require.NoError(t, log.Record(t.Context(), []entities.Change{
+			{Kind: entities.ChangeAdded, Book: entities.Book{ID: string(rune('a' + i%26))}, At: time.Unix(int64(i), 0)},
+		}))
+	}
+
+	all, err := log.Recent(t.Context(), "", 0)
+	require.NoError(t, err)
+	require.Len(t, all, maxChanges)
+	assert.Equal(t, time.Unix(maxChanges+9, 0), all[0].At)
+}
+
+func TestChangeLogSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	change := entities.Change{
+		Kind: entities.ChangeUnlocked,
+		Book: entities.Book{
+			ID:           "1",
+			Title:        "Issue 1",
+			Link:         "http://localhost/1.pdf",
+			Category:     "MagPI",
+			Availability: entities.AvailabilityAvailable,
+		},
+		At: at,
+	}
+	require.NoError(t, NewChangeLog(dir).Record(t.Context(), []entities.Change{change}))
+
+	restarted := NewChangeLog(dir)
+	require.NoError(t, restarted.Load(t.Context()))
+	all, err := restarted.Recent(t.Context(), "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []entities.Change{change}, all)
+}
+
+func TestChangeLogRejectsUnknownVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, changesFileName), []byte(`{"version": 99}`), 0o644))
+	assert.Error(t, NewChangeLog(dir).Load(t.Context()))
+}
@@ -0,0 +1,21 @@
+package bookshelf
+
+import "time"
+
+// Clock tells the time and waits for it to pass.
+// It is replaced in tests so they do not sleep.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
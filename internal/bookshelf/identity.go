@@ -0,0 +1,158 @@
+package bookshelf
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"maps"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+// The ID of a book is derived from the keys its source gives it, which do
+// not change when the book is republished: the name of its file, such as
+// MagPi150.pdf which gives magpi150, or else its category and issue number,
+// such as magpi-150, or else its category and title. The
+// cover URL is not used, a move of the covers to another CDN must not
+// change the IDs.
+//
+// When the ID of a book still changes, for instance when its file is
+// renamed, the previous ID is kept as an alias of the new one, so the links
+// to the previous ID keep working.
+
+// assignIDs gives an ID to the books that do not have one yet. The IDs of
+// the other books, such as the restored ones, are kept and reserved.
+func assignIDs(books []entities.Book) {
+	taken := make(map[string]bool, len(books))
+	for _, b := range books {
+		if b.ID != "" {
+			taken[b.ID] = true
+		}
+	}
+
+	for i := range books {
+		if books[i].ID != "" {
+			continue
+		}
+		books[i].ID = uniqueID(books[i], taken)
+		taken[books[i].ID] = true
+	}
+}
+
+// uniqueID returns the ID of the book, unless it is taken by another book.
+// The name of the source is then appended, then a number.
+func uniqueID(b entities.Book, taken map[string]bool) string {
+	id := baseID(b)
+	if !taken[id] {
+		return id
+	}
+	if source := entities.Slug(b.Source); source != "" {
+		id += "-" + source
+		if !taken[id] {
+			return id
+		}
+	}
+	for n := 2; ; n++ {
+		if candidate := id + "-" + strconv.Itoa(n); !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// baseID returns the ID derived from the name of the file of the book, or
+// else from its category and issue number, or else from its category and
+// title.
+func baseID(b entities.Book) string {
+	if b.File != "" {
+		if id := entities.Slug(strings.TrimSuffix(b.File, path.Ext(b.File))); id != "" {
+			return id
+		}
+	}
+	if category := entities.Slug(b.Category); category != "" && b.Issue > 0 {
+		return category + "-" + strconv.Itoa(b.Issue)
+	}
+	if id := entities.Slug(b.Category + " " + b.Title); id != "" {
+		return id
+	}
+	return legacyID(b)
+}
+
+// legacyID returns the ID books had before they were derived from the keys
+// of their source, it is only used to migrate the links to these IDs.
+func legacyID(b entities.Book) string {
+	sum := sha1.Sum([]byte(b.Cover + ":" + b.Title))
+	return hex.EncodeToString(sum[:])
+}
+
+// identityKeys return the keys identifying a book within its source,
+// whatever its ID: the name of its file, when it has one, and its category
+// and title.
+func identityKeys(b entities.Book) []string {
+	keys := make([]string, 0, 2)
+	if b.File != "" {
+		keys = append(keys, b.Source+"\x00file\x00"+strings.ToLower(b.File))
+	}
+	return append(keys, b.Source+"\x00title\x00"+b.Category+"\x00"+b.Title)
+}
+
+// updateAliases returns the aliases after the previous books were replaced
+// by the current ones. A previous book found in the current catalog under
+// another ID, by its file, by its title or by its legacy ID, becomes an
+// alias of its current ID. Keys shared by several previous books are
+// ambiguous and ignored.
+// Current IDs are never aliases, and aliases always point to a current or
+// removed ID, never to another alias.
+func updateAliases(aliases map[string]string, previous, current []entities.Book) map[string]string {
+	result := maps.Clone(aliases)
+	if result == nil {
+		result = map[string]string{}
+	}
+
+	previousByKey := make(map[string]string, 2*len(previous))
+	previousIDs := make(map[string]bool, len(previous))
+	for _, b := range previous {
+		for _, key := range identityKeys(b) {
+			if _, ok := previousByKey[key]; ok {
+				previousByKey[key] = ""
+			} else {
+				previousByKey[key] = b.ID
+			}
+		}
+		previousIDs[b.ID] = true
+	}
+
+	currentIDs := make(map[string]bool, len(current))
+	for _, b := range current {
+		currentIDs[b.ID] = true
+		if id := legacyID(b); id != b.ID && previousIDs[id] {
+			result[id] = b.ID
+			continue
+		}
+		for _, key := range identityKeys(b) {
+			if id, ok := previousByKey[key]; ok {
+				if id != "" && id != b.ID {
+					result[id] = b.ID
+				}
+				break
+			}
+		}
+	}
+
+	for id := range currentIDs {
+		delete(result, id)
+	}
+	for from, to := range result {
+		// follow the chains left by successive renames
+		for seen := 0; seen < len(result); seen++ {
+			next, ok := result[to]
+			if !ok || next == from {
+				break
+			}
+			to = next
+		}
+		result[from] = to
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package bookshelf
+
+import (
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ids(books []entities.Book) []string {
+	result := make([]string, 0, len(books))
+	for _, b := range books {
+		result = append(result, b.ID)
+	}
+	return result
+}
+
+func TestAssignIDs(t *testing.T) {
+	books := []entities.Book{
+		{Title: "The MagPi 150", File: "MagPi150.pdf", Category: "MagPI", Source: "magpi"},
+		{Title: "Coming soon", File: "Book_of_Making_2026.pdf", Category: "Book", Source: "magpi"},
+		{Title: "Pico Guide", Category: "Book", Source: "magpi"},
+		{Title: "The MagPi 151", Category: "MagPI", Issue: 151, Source: "magpi"},
+		{Title: "The MagPi 150 copy", File: "magpi150.PDF", Category: "MagPI", Source: "nas"},
+		{Title: "The MagPi 150 again", File: "MagPi150.pdf", Category: "MagPI", Source: "nas"},
+		{Title: "Restored", File: "Restored.pdf", ID: "pico-guide"},
+	}
+	assignIDs(books)
+
+	assert.Equal(t, []string{
+		"magpi150",
+		"book-of-making-2026",
+		"book-pico-guide",
+		"magpi-151",
+		"magpi150-nas",
+		"magpi150-nas-2",
+		"pico-guide",
+	}, ids(books))
+}
+
+func TestIDsDoNotDependOnCovers(t *testing.T) {
+	book := entities.Book{Title: "The MagPi 150", Cover: "http://cdn1/150.jpg", File: "MagPi150.pdf"}
+	moved := book
+	moved.Cover = "http://cdn2/150.jpg"
+	noFile := entities.Book{Title: "The MagPi 150", Category: "MagPI", Cover: "http://cdn1/150.jpg"}
+	noFileMoved := noFile
+	noFileMoved.Cover = "http://cdn2/150.jpg"
+
+	assert.Equal(t, baseID(book), baseID(moved))
+	assert.Equal(t, baseID(noFile), baseID(noFileMoved))
+}
+
+func TestStorageKeepsAliases(t *testing.T) {
+	subject := NewStorage()
+	book := entities.Book{Title: "The MagPi 150", File: "MagPi150.pdf", Cover: "http://cdn1/150.jpg", Category: "MagPI"}
+
+	// a catalog persisted with the legacy IDs
+	legacy := book
+	legacy.ID = legacyID(book)
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{legacy}))
+
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{book}))
+	page, err := subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"magpi150"}, ids(page.Books))
+	current, ok := subject.ResolveAlias(t.Context(), legacy.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "magpi150", current)
+
+	// the file is renamed upstream, both former IDs point to the new one
+	renamed := book
+	renamed.File = "MagPi-150.pdf"
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{renamed}))
+
+	for _, former := range []string{legacy.ID, "magpi150"} {
+		current, ok = subject.ResolveAlias(t.Context(), former)
+		assert.True(t, ok, former)
+		assert.Equal(t, "magpi-150", current, former)
+	}
+	_, ok = subject.ResolveAlias(t.Context(), "magpi-150")
+	assert.False(t, ok, "current IDs are never aliases")
+}
+
+func TestAmbiguousBooksAreNotAliased(t *testing.T) {
+	subject := NewStorage()
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{
+		{ID: "first", Title: "Special", Category: "Book"},
+		{ID: "second", Title: "Special", Category: "Book"},
+	}))
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{{Title: "Special", Category: "Book"}}))
+
+	_, ok := subject.ResolveAlias(t.Context(), "first")
+	assert.False(t, ok)
+	_, ok = subject.ResolveAlias(t.Context(), "second")
+	assert.False(t, ok)
+}
+
+func TestUpdateAliasesFollowsRenames(t *testing.T) {
+	v1 := []entities.Book{{ID: "a", Title: "Issue 1", File: "one.pdf"}}
+	v2 := []entities.Book{{ID: "b", Title: "Issue 1", File: "one.pdf"}}
+	v3 := []entities.Book{{ID: "c", Title: "Issue 1", File: "one.pdf"}}
+
+	aliases := updateAliases(nil, v1, v2)
+	assert.Equal(t, map[string]string{"a": "b"}, aliases)
+
+	aliases = updateAliases(aliases, v2, v3)
+	assert.Equal(t, map[string]string{"a": "c", "b": "c"}, aliases)
+
+	aliases = updateAliases(aliases, v3, v1)
+	assert.Equal(t, map[string]string{"b": "a", "c": "a"}, aliases, "no alias loops back to itself")
+}
+
+func TestPersistentStorageKeepsAliases(t *testing.T) {
+	dataDir := t.TempDir()
+	book := entities.Book{Title: "The MagPi 150", File: "MagPi150.pdf", Cover: "http://cdn1/150.jpg", Category: "MagPI"}
+	legacy := book
+	legacy.ID = legacyID(book)
+
+	subject := NewPersistentStorage(dataDir)
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{legacy}))
+	require.NoError(t, subject.ReplaceAll(t.Context(), []entities.Book{book}))
+
+	restarted := NewPersistentStorage(dataDir)
+	require.NoError(t, restarted.Load(t.Context()))
+	current, ok := restarted.ResolveAlias(t.Context(), legacy.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "magpi150", current)
+}
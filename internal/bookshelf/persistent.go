@@ -0,0 +1,214 @@
+package bookshelf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/atomicfile"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+const (
+	// catalogFileName is the name of the catalog file inside the data directory.
+	catalogFileName = "catalog.json"
+	// catalogFileVersion is the current version of the catalog file format.
+	// Bump it when a change cannot be read by older versions, adding new
+	// optional fields does not require a new version.
+	catalogFileVersion = 1
+)
+
+// catalogFile is the on-disk representation of the catalog.
+type catalogFile struct {
+	Version int           `json:"version"`
+	SavedAt time.Time     `json:"savedAt"`
+	Books   []catalogBook `json:"books"`
+	// Aliases maps the former IDs of books to their current ID.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// catalogBook is the on-disk representation of a book.
+// It is kept apart from entities.Book so the domain can change without
+// breaking the files that were already written.
+type catalogBook struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Cover       string `json:"cover,omitempty"`
+	Link        string `json:"link,omitempty"`
+	Category    string `json:"category"`
+	File        string `json:"file,omitempty"`
+	// Availability is missing from the files written before it was added.
+	Availability entities.Availability `json:"availability,omitempty"`
+	Source       string                `json:"source,omitempty"`
+	Issue        int                   `json:"issue,omitempty"`
+	Year         int                   `json:"year,omitempty"`
+	Month        time.Month            `json:"month,omitempty"`
+	Edition      int                   `json:"edition,omitempty"`
+	// Added is missing from the files written before it was added, these
+	// books are considered added when the file is loaded.
+	Added time.Time `json:"added,omitzero"`
+}
+
+// PersistentStorage is a Storage that keeps a copy of the last good catalog
+// on disk, so the bookshelf can be served after a restart even if the
+// upstream source is not reachable.
+type PersistentStorage struct {
+	*Storage
+	path string
+	// loadedSavedAt is when the catalog read by Load was saved.
+	loadedSavedAt atomic.Pointer[time.Time]
+}
+
+// NewPersistentStorage creates a new instance of PersistentStorage that
+// keeps its catalog in the given data directory.
+// The directory is created on the first write if it does not exist.
+func NewPersistentStorage(dataDir string) *PersistentStorage {
+	return &PersistentStorage{
+		Storage: NewStorage(),
+		path:    filepath.Join(dataDir, catalogFileName),
+	}
+}
+
+// Load reads the catalog file, if present, and publishes its books.
+// A missing file is not an error, the storage is simply left empty.
+func (s *PersistentStorage) Load(ctx context.Context) error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.InfoContext(ctx, "no persisted catalog found", slog.String("path", s.path))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read catalog file: %w", err)
+	}
+
+	var file catalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cannot decode catalog file: %w", err)
+	}
+	if file.Version < 1 || file.Version > catalogFileVersion {
+		return fmt.Errorf("unsupported catalog file version %d", file.Version)
+	}
+
+	books := make([]entities.Book, 0, len(file.Books))
+	for _, b := range file.Books {
+		books = append(books, b.toBookEntity())
+	}
+
+	c := s.buildCatalog(ctx, books)
+	maps.Copy(c.aliases, file.Aliases)
+	s.catalog.Store(c)
+
+	s.loadedSavedAt.Store(&file.SavedAt)
+	slog.InfoContext(ctx, "loaded persisted catalog",
+		slog.String("path", s.path),
+		slog.Int("size", len(books)),
+		slog.Time("savedAt", file.SavedAt),
+	)
+	return nil
+}
+
+// LoadedSavedAt returns when the catalog read by Load was saved, or the zero
+// time when no persisted catalog was loaded.
+func (s *PersistentStorage) LoadedSavedAt() time.Time {
+	if t := s.loadedSavedAt.Load(); t != nil {
+		return *t
+	}
+	return time.Time{}
+}
+
+// ReplaceAll replaces all books in storage with the provided list and
+// writes the new catalog to disk.
+// The in-memory catalog is updated even if it cannot be persisted, in that
+// case the returned error describes the write failure.
+func (s *PersistentStorage) ReplaceAll(ctx context.Context, books []entities.Book) error {
+	c := s.buildCatalog(ctx, books)
+	s.catalog.Store(c)
+
+	if err := s.save(c); err != nil {
+		return fmt.Errorf("cannot persist catalog: %w", err)
+	}
+	return nil
+}
+
+// save atomically writes the catalog to disk by writing a temporary file in
+// the same directory and renaming it over the previous one.
+func (s *PersistentStorage) save(c *catalog) error {
+	file := catalogFile{
+		Version: catalogFileVersion,
+		SavedAt: time.Now().UTC(),
+		Books:   make([]catalogBook, 0, len(c.books)),
+		Aliases: c.aliases,
+	}
+	for _, b := range c.books {
+		file.Books = append(file.Books, newCatalogBook(b))
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(s.path, data)
+}
+
+func newCatalogBook(b entities.Book) catalogBook {
+	return catalogBook{
+		ID:           b.ID,
+		Title:        b.Title,
+		Description:  b.Description,
+		Cover:        b.Cover,
+		Link:         b.Link,
+		Category:     b.Category,
+		File:         b.File,
+		Availability: b.Availability,
+		Source:       b.Source,
+		Issue:        b.Issue,
+		Year:         b.Year,
+		Month:        b.Month,
+		Edition:      b.Edition,
+		Added:        b.Added,
+	}
+}
+
+func (b catalogBook) toBookEntity() entities.Book {
+	availability := b.Availability
+	if availability == "" {
+		// files written before the availability was stored only had
+		// a link for the books that could be downloaded
+		availability = entities.AvailabilityAvailable
+		if b.Link == "" {
+			availability = entities.AvailabilityLocked
+		}
+	}
+
+	return entities.Book{
+		ID:           b.ID,
+		Title:        b.Title,
+		Description:  b.Description,
+		Cover:        b.Cover,
+		Link:         b.Link,
+		Category:     b.Category,
+		File:         b.File,
+		Availability: availability,
+		Source:       b.Source,
+		Issue:        b.Issue,
+		Year:         b.Year,
+		Month:        b.Month,
+		Edition:      b.Edition,
+		Added:        b.Added,
+	}
+}
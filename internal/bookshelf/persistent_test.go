@@ -0,0 +1,79 @@
+package bookshelf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentStorageRoundTrip(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+
+	subject := NewPersistentStorage(dataDir)
+	require.NoError(t, subject.Load(t.Context()), "a missing file is not an error")
+	assert.True(t, subject.LoadedSavedAt().IsZero())
+	require.NoError(t, subject.ReplaceAll(t.Context(), generation(1)))
+
+	saved, err := subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary files must not be left behind")
+	assert.Equal(t, catalogFileName, entries[0].Name())
+
+	restarted := NewPersistentStorage(dataDir)
+	require.NoError(t, restarted.Load(t.Context()))
+	assert.WithinDuration(t, time.Now(), restarted.LoadedSavedAt(), time.Minute)
+
+	loaded, err := restarted.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, saved, loaded)
+
+	categories, err := restarted.GetCategories(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Book", "MagPI"}, categories)
+}
+
+func TestPersistentStorageLoadIgnoresUnknownFields(t *testing.T) {
+	dataDir := t.TempDir()
+	content := `{
+		"version": 1,
+		"savedAt": "2025-01-01T00:00:00Z",
+		"futureField": true,
+		"books": [
+			{"id": "abc", "title": "Issue 1", "category": "MagPI", "futureField": 1}
+		]
+	}`
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, catalogFileName), []byte(content), 0o644))
+
+	subject := NewPersistentStorage(dataDir)
+	require.NoError(t, subject.Load(t.Context()))
+
+	book, err := subject.GetByID(t.Context(), "abc")
+	require.NoError(t, err)
+	require.NotNil(t, book)
+	assert.WithinDuration(t, time.Now(), book.Added, time.Minute, "the file predates the field")
+	book.Added = time.Time{}
+	assert.Equal(t, entities.Book{
+		ID:       "abc",
+		Title:    "Issue 1",
+		Category: "MagPI",
+		// derived from the missing link, the file predates the field
+		Availability: entities.AvailabilityLocked,
+	}, *book)
+}
+
+func TestPersistentStorageLoadRejectsNewerVersion(t *testing.T) {
+	dataDir := t.TempDir()
+	content := `{"version": 999, "books": []}`
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, catalogFileName), []byte(content), 0o644))
+
+	subject := NewPersistentStorage(dataDir)
+	assert.ErrorContains(t, subject.Load(t.Context()), "unsupported catalog file version 999")
+}
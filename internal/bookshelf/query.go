@@ -0,0 +1,89 @@
+package bookshelf
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+// queryBooks selects, sorts and pages books as described by the query.
+// The books are not modified, they are copied before being filtered or
+// sorted. Books that compare equal keep their order.
+func queryBooks(books []entities.Book, q entities.BookQuery) (entities.BookPage, error) {
+	if q.Category != "" || q.Availability != "" {
+		books = slices.DeleteFunc(slices.Clone(books), func(b entities.Book) bool {
+			return (q.Category != "" && b.Category != q.Category) ||
+				(q.Availability != "" && b.Availability != q.Availability)
+		})
+	}
+	if compare := compareBooks(q.Sort, q.Order); compare != nil {
+		books = slices.Clone(books)
+		slices.SortStableFunc(books, compare)
+	}
+
+	start := 0
+	if q.Cursor != "" {
+		i := slices.IndexFunc(books, func(b entities.Book) bool { return b.ID == q.Cursor })
+		if i < 0 {
+			return entities.BookPage{}, entities.ErrUnknownCursor
+		}
+		start = i + 1
+	}
+	end := len(books)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	page := entities.BookPage{
+		Books: books[start:end],
+		Total: len(books),
+	}
+	if end < len(books) {
+		page.Next = books[end-1].ID
+	}
+	return page, nil
+}
+
+// compareBooks returns the comparison of the books for the sort, or nil
+// when the books keep their order.
+func compareBooks(field entities.SortField, order entities.SortOrder) func(a, b entities.Book) int {
+	if order == entities.OrderDefault {
+		order = field.DefaultOrder()
+	}
+	desc := order == entities.OrderDesc
+	switch field {
+	case entities.SortTitle:
+		return func(a, b entities.Book) int {
+			return reverseIf(desc, strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)))
+		}
+	case entities.SortIssue:
+		return func(a, b entities.Book) int {
+			// the books without an issue number come last in both orders
+			switch {
+			case a.Issue == 0 && b.Issue == 0:
+				return 0
+			case a.Issue == 0:
+				return 1
+			case b.Issue == 0:
+				return -1
+			default:
+				return reverseIf(desc, cmp.Compare(a.Issue, b.Issue))
+			}
+		}
+	case entities.SortAdded:
+		return func(a, b entities.Book) int {
+			return reverseIf(desc, a.Added.Compare(b.Added))
+		}
+	default:
+		return nil
+	}
+}
+
+func reverseIf(reverse bool, c int) int {
+	if reverse {
+		return -c
+	}
+	return c
+}
@@ -0,0 +1,103 @@
+package bookshelf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queryFixture() []entities.Book {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	books := []entities.Book{
+		{ID: "magpi-149", Title: "The MagPi 149", Category: "MagPI", Issue: 149, Added: day},
+		{ID: "beginners", Title: "beginner's Guide", Category: "Book", Added: day.Add(48 * time.Hour)},
+		{ID: "magpi-151", Title: "The MagPi 151", Category: "MagPI", Issue: 151, Added: day.Add(24 * time.Hour), Availability: entities.AvailabilityLocked},
+		{ID: "annual", Title: "Annual 2025", Category: "MagPI", Added: day},
+		{ID: "magpi-150", Title: "The MagPi 150", Category: "MagPI", Issue: 150, Added: day},
+	}
+	for i := range books {
+		if books[i].Availability == "" {
+			books[i].Availability = entities.AvailabilityAvailable
+		}
+	}
+	return books
+}
+
+func TestQueryBooksSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    entities.BookQuery
+		expected []string
+	}{
+		{
+			name:     "order of the sources",
+			expected: []string{"magpi-149", "beginners", "magpi-151", "annual", "magpi-150"},
+		},
+		{
+			name:     "title ignores the case",
+			query:    entities.BookQuery{Sort: entities.SortTitle},
+			expected: []string{"annual", "beginners", "magpi-149", "magpi-150", "magpi-151"},
+		},
+		{
+			name:     "title descending",
+			query:    entities.BookQuery{Sort: entities.SortTitle, Order: entities.OrderDesc},
+			expected: []string{"magpi-151", "magpi-150", "magpi-149", "beginners", "annual"},
+		},
+		{
+			name:     "newest issue first by default, unknown issues last",
+			query:    entities.BookQuery{Sort: entities.SortIssue},
+			expected: []string{"magpi-151", "magpi-150", "magpi-149", "beginners", "annual"},
+		},
+		{
+			name:     "oldest issue first, unknown issues still last",
+			query:    entities.BookQuery{Sort: entities.SortIssue, Order: entities.OrderAsc},
+			expected: []string{"magpi-149", "magpi-150", "magpi-151", "beginners", "annual"},
+		},
+		{
+			name:     "last added first by default, ties keep their order",
+			query:    entities.BookQuery{Sort: entities.SortAdded},
+			expected: []string{"beginners", "magpi-151", "magpi-149", "annual", "magpi-150"},
+		},
+		{
+			name:     "filtered by category and availability",
+			query:    entities.BookQuery{Category: "MagPI", Availability: entities.AvailabilityAvailable, Sort: entities.SortIssue},
+			expected: []string{"magpi-150", "magpi-149", "annual"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := queryFixture()
+			page, err := queryBooks(books, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(page.Books))
+			assert.Equal(t, len(tt.expected), page.Total)
+			assert.Empty(t, page.Next)
+			assert.Equal(t, "magpi-149", books[0].ID, "the books are not modified")
+		})
+	}
+}
+
+func TestQueryBooksPages(t *testing.T) {
+	query := entities.BookQuery{Sort: entities.SortIssue, Limit: 2}
+
+	var seen []string
+	for range 3 {
+		page, err := queryBooks(queryFixture(), query)
+		require.NoError(t, err)
+		assert.Equal(t, 5, page.Total)
+		seen = append(seen, ids(page.Books)...)
+		query.Cursor = page.Next
+	}
+
+	assert.Equal(t, []string{"magpi-151", "magpi-150", "magpi-149", "beginners", "annual"}, seen)
+	assert.Empty(t, query.Cursor, "the last page has no next cursor")
+}
+
+func TestQueryBooksUnknownCursor(t *testing.T) {
+	_, err := queryBooks(queryFixture(), entities.BookQuery{Limit: 2, Cursor: "removed"})
+	assert.ErrorIs(t, err, entities.ErrUnknownCursor)
+}
@@ -0,0 +1,106 @@
+package bookshelf
+
+import (
+	"encoding/xml"
+	"os"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/adapters"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestdataStorage loads the bookshelf used by the adapter tests.
+func newTestdataStorage(t *testing.T) *Storage {
+	t.Helper()
+	f, err := os.Open("../adapters/testdata/bookshelf.xml")
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var shelf adapters.BookshelfXML
+	require.NoError(t, xml.NewDecoder(f).Decode(&shelf))
+
+	books := []entities.Book{}
+	for _, item := range shelf.MagPi {
+		item.Category = "MagPI"
+		books = append(books, item.ToBookEntity())
+	}
+	for _, item := range shelf.Books {
+		item.Category = "Book"
+		books = append(books, item.ToBookEntity())
+	}
+
+	storage := NewStorage()
+	require.NoError(t, storage.ReplaceAll(t.Context(), books))
+	return storage
+}
+
+func titles(books []entities.Book) []string {
+	result := make([]string, 0, len(books))
+	for _, b := range books {
+		result = append(result, b.Title)
+	}
+	return result
+}
+
+func TestSearchRelevance(t *testing.T) {
+	storage := newTestdataStorage(t)
+
+	tests := []struct {
+		name     string
+		category string
+		query    string
+		expected []string
+	}{
+		{
+			name:  "exact issue comes first",
+			query: "mag 2",
+			expected: []string{
+				"MagPI Available Mag 2",
+				"MagPI Locked Mag 1",
+				"MagPI Available Mag 3",
+			},
+		},
+		{
+			name:  "title matches rank above description matches",
+			query: "book",
+			expected: []string{
+				"Available Book 1",
+				"Some non available book yet",
+			},
+		},
+		{
+			name:     "category filter",
+			category: "Book",
+			query:    "available",
+			expected: []string{
+				"Available Book 1",
+				"Some non available book yet",
+			},
+		},
+		{
+			name:     "description only",
+			query:    "FORBIDDEN",
+			expected: []string{"Some non available book yet"},
+		},
+		{
+			name:     "prefix of the last word",
+			query:    "forbid",
+			expected: []string{"Some non available book yet"},
+		},
+		{
+			name:     "no match",
+			query:    "robot arm",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Search(t.Context(), tt.query, entities.BookQuery{Category: tt.category})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, titles(result.Books))
+		})
+	}
+}
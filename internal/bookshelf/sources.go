@@ -0,0 +1,253 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+// Source is a named BookClient aggregated by a MultiSourceClient.
+type Source struct {
+	Name string
+	// Namespace is prepended to the categories of the books of the source,
+	// so books of different sources are not mixed in the same category.
+	Namespace string
+	Client    BookClient
+}
+
+// sourceState is what is known about a source after its last refresh.
+type sourceState struct {
+	// books is the last good result of the source.
+	books  []entities.Book
+	loaded bool
+	health entities.SourceHealth
+}
+
+// StaleError is returned by MultiSourceClient.GetBooks when every source
+// failed but some of them still have their last good books, which are
+// attached. The refresh failed, even if these books can still be served.
+type StaleError struct {
+	Books []entities.Book
+	Err   error
+}
+
+func (e *StaleError) Error() string {
+	return "every source failed, only their last good books are known: " + e.Err.Error()
+}
+
+func (e *StaleError) Unwrap() error {
+	return e.Err
+}
+
+// MultiSourceClient is a BookClient that fetches the books of several
+// sources concurrently and merges them.
+// A failing source does not fail the whole refresh, its last good books are
+// used instead, so an outage does not remove them from the catalog.
+type MultiSourceClient struct {
+	sources []Source
+
+	mu     sync.RWMutex
+	states []sourceState
+}
+
+// NewMultiSourceClient creates a new instance of MultiSourceClient.
+// The books are merged in the order of the sources, when the same book is
+// found in several sources the first one is kept, unless only a later one
+// has a PDF to download.
+func NewMultiSourceClient(sources ...Source) *MultiSourceClient {
+	states := make([]sourceState, len(sources))
+	for i, s := range sources {
+		states[i].health.Name = s.Name
+	}
+	return &MultiSourceClient{
+		sources: sources,
+		states:  states,
+	}
+}
+
+// GetBooks fetches the books of every source and merges them.
+// It fails when no source returned books or reported them unchanged, with a
+// *StaleError when some sources still have their last good books. It
+// returns entities.ErrUnchanged when no source returned new books and at
+// least one reported that its books are unchanged.
+func (c *MultiSourceClient) GetBooks(ctx context.Context) ([]entities.Book, error) {
+	results := make([][]entities.Book, len(c.sources))
+	errs := make([]error, len(c.sources))
+
+	var wg sync.WaitGroup
+	for i, s := range c.sources {
+		wg.Go(func() {
+			results[i], errs[i] = s.Client.GetBooks(ctx)
+		})
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().UTC()
+	loaded, fresh, unchanged := false, false, false
+	for i, s := range c.sources {
+		state := &c.states[i]
+		switch {
+		case errors.Is(errs[i], entities.ErrUnchanged) && state.loaded:
+			unchanged = true
+			state.health.Healthy = true
+			state.health.LastSuccess = now
+		case errs[i] != nil:
+			slog.ErrorContext(ctx, "failed to get books from source",
+				slog.String("source", s.Name),
+				slog.Bool("keepingLastGoodData", state.loaded),
+				slog.Any("error", errs[i]),
+			)
+			state.health.Healthy = false
+			state.health.LastError = errs[i].Error()
+			state.health.LastErrorAt = now
+		default:
+			fresh = true
+			state.books = fromSource(results[i], s)
+			state.loaded = true
+			state.health.Healthy = true
+			state.health.Books = len(state.books)
+			state.health.LastSuccess = now
+		}
+		loaded = loaded || state.loaded
+	}
+
+	if !loaded {
+		return nil, fmt.Errorf("no source is available: %w", errors.Join(errs...))
+	}
+	if !fresh && !unchanged {
+		return nil, &StaleError{Books: c.merge(), Err: errors.Join(errs...)}
+	}
+	if unchanged && !fresh {
+		return nil, entities.ErrUnchanged
+	}
+
+	return c.merge(), nil
+}
+
+// Restore uses the books of a previous catalog, such as the persisted one,
+// as the last good data of the sources that were not refreshed yet.
+// A source that is down after a restart then keeps its books.
+func (c *MultiSourceClient) Restore(books []entities.Book) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, s := range c.sources {
+		state := &c.states[i]
+		if state.loaded {
+			continue
+		}
+		for _, b := range books {
+			if b.Source == s.Name {
+				state.books = append(state.books, b)
+			}
+		}
+		state.loaded = len(state.books) > 0
+		state.health.Books = len(state.books)
+	}
+}
+
+// Health returns the health of every source, in the order of the sources.
+func (c *MultiSourceClient) Health(_ context.Context) ([]entities.SourceHealth, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]entities.SourceHealth, 0, len(c.states))
+	for _, s := range c.states {
+		result = append(result, s.health)
+	}
+	return result, nil
+}
+
+// merge concatenates the last good books of every source, without the
+// books already published by a previous source. Books are the same when
+// they share their PDF link, or the name of their PDF, their issue number
+// or their title within the category their source gave them, see mergeKeys.
+// The books of a source are never merged together.
+// It must be called with the lock held.
+func (c *MultiSourceClient) merge() []entities.Book {
+	var result []entities.Book
+	// origins holds the index of the source of every merged book
+	var origins []int
+	byKey := map[string]int{}
+
+	for i, s := range c.states {
+		for _, b := range s.books {
+			keys := mergeKeys(b, c.sources[i].Namespace)
+			j, ok := 0, false
+			for _, key := range keys {
+				if j, ok = byKey[key]; ok && origins[j] != i {
+					break
+				}
+				ok = false
+			}
+			if ok {
+				// a source publishing the PDF wins over one that announces it
+				if result[j].Link == "" && b.Link != "" {
+					result[j] = b
+					origins[j] = i
+					for _, key := range keys {
+						byKey[key] = j
+					}
+				}
+				continue
+			}
+
+			for _, key := range keys {
+				if _, found := byKey[key]; !found {
+					byKey[key] = len(result)
+				}
+			}
+			result = append(result, b)
+			origins = append(origins, i)
+		}
+	}
+	return result
+}
+
+// mergeKeys returns the keys identifying a book across sources: its PDF
+// link, and the name of its PDF, its issue number and its title within its
+// category, without the namespace of the source. Names that are not the
+// name of a PDF, such as the download links of some feeds, are not used.
+func mergeKeys(b entities.Book, namespace string) []string {
+	category := b.Category
+	if namespace != "" {
+		category = strings.TrimPrefix(category, namespace+"/")
+	}
+
+	keys := make([]string, 0, 4)
+	if b.Link != "" {
+		keys = append(keys, "link\x00"+b.Link)
+	}
+	if strings.EqualFold(path.Ext(b.File), ".pdf") {
+		keys = append(keys, "file\x00"+category+"\x00"+strings.ToLower(b.File))
+	}
+	if b.Issue > 0 {
+		keys = append(keys, "issue\x00"+category+"\x00"+strconv.Itoa(b.Issue))
+	}
+	return append(keys, "title\x00"+category+"\x00"+b.Title)
+}
+
+// fromSource returns a copy of books tagged with the source name and with
+// the source namespace prepended to their category.
+func fromSource(books []entities.Book, source Source) []entities.Book {
+	result := make([]entities.Book, 0, len(books))
+	for _, b := range books {
+		b.Source = source.Name
+		if source.Namespace != "" {
+			b.Category = source.Namespace + "/" + b.Category
+		}
+		result = append(result, b)
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+package bookshelf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func categoryTitles(books []entities.Book) []string {
+	result := make([]string, 0, len(books))
+	for _, b := range books {
+		result = append(result, b.Category+":"+b.Title)
+	}
+	return result
+}
+
+func TestMultiSourceClientMergesSources(t *testing.T) {
+	magpi := &fakeBookClient{books: []entities.Book{
+		{Title: "Issue 1", Cover: "c1", Link: "http://localhost/1.pdf", Category: "MagPI"},
+		{Title: "Issue 2", Cover: "c2", Category: "MagPI"},
+		{Title: "The MagPi 150", Cover: "c150", File: "MagPi150.pdf", Category: "MagPI"},
+	}}
+	nas := &fakeBookClient{books: []entities.Book{
+		{Title: "Issue 1 copy", Cover: "other", Link: "http://localhost/1.pdf", Category: "MagPI"},
+		{Title: "Issue 2", Cover: "nas/c2", Link: "http://nas/2.pdf", Category: "MagPI"},
+		{Title: "MagPi issue 150", File: "magpi150.pdf", Link: "http://nas/magpi150.pdf", Category: "MagPI"},
+		{Title: "Training", Cover: "c3", Link: "http://nas/training.pdf", Category: "Docs"},
+	}}
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: magpi},
+		Source{Name: "nas", Namespace: "NAS", Client: nas},
+	)
+
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MagPI:Issue 1", "NAS/MagPI:Issue 2", "NAS/MagPI:MagPi issue 150", "NAS/Docs:Training"}, categoryTitles(books),
+		"duplicates are dropped, whatever their cover, unless they unlock a book")
+	assert.Equal(t, "http://nas/2.pdf", books[1].Link)
+}
+
+func TestMultiSourceClientMergesOnlyAcrossSources(t *testing.T) {
+	nas := &fakeBookClient{books: []entities.Book{
+		{Title: "Issue 1", File: "issue-01.pdf", Link: "/files/nas/HackSpace/issue-01.pdf", Category: "HackSpace"},
+		{Title: "Issue 1", File: "issue-01.pdf", Link: "/files/nas/Wireframe/issue-01.pdf", Category: "Wireframe"},
+	}}
+	makers := &fakeBookClient{books: []entities.Book{
+		{Title: "Episode 1", File: "download", Link: "https://makers.example.com/1/download", Category: "Makers"},
+		{Title: "Episode 2", File: "download", Link: "https://makers.example.com/2/download", Category: "Makers"},
+	}}
+	mirror := &fakeBookClient{books: []entities.Book{
+		{Title: "HackSpace 1", File: "issue-01.pdf", Link: "http://mirror/hs1.pdf", Category: "HackSpace"},
+		{Title: "Episode 3", File: "download", Link: "https://mirror.example.com/3/download", Category: "Makers"},
+		{Title: "Issue 1", File: "issue-01.pdf", Link: "http://mirror/wf1.pdf", Category: "Wireframe"},
+		{Title: "Issue 1", File: "issue-01.pdf", Link: "http://mirror/mp1.pdf", Category: "MagPI"},
+	}}
+	subject := NewMultiSourceClient(
+		Source{Name: "nas", Client: nas},
+		Source{Name: "makers", Client: makers},
+		Source{Name: "mirror", Client: mirror},
+	)
+
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"HackSpace:Issue 1", "Wireframe:Issue 1",
+		"Makers:Episode 1", "Makers:Episode 2",
+		"Makers:Episode 3", "MagPI:Issue 1",
+	}, categoryTitles(books), "only the same file in the same category is published by several sources")
+}
+
+func TestMultiSourceClientKeepsLastGoodData(t *testing.T) {
+	magpi := &fakeBookClient{books: []entities.Book{{Title: "Issue 1", Category: "MagPI"}}}
+	nas := &fakeBookClient{books: []entities.Book{{Title: "Training", Category: "Docs"}}}
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: magpi},
+		Source{Name: "nas", Client: nas},
+	)
+
+	_, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+
+	nas.err = errors.New("nas is down")
+	nas.books = nil
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MagPI:Issue 1", "Docs:Training"}, categoryTitles(books))
+
+	health, err := subject.Health(t.Context())
+	require.NoError(t, err)
+	require.Len(t, health, 2)
+	assert.Equal(t, "magpi", health[0].Name)
+	assert.True(t, health[0].Healthy)
+	assert.Equal(t, 1, health[0].Books)
+	assert.Equal(t, "nas", health[1].Name)
+	assert.False(t, health[1].Healthy)
+	assert.Equal(t, "nas is down", health[1].LastError)
+	assert.Equal(t, 1, health[1].Books, "the last good books are still served")
+	assert.False(t, health[1].LastSuccess.IsZero())
+}
+
+func TestMultiSourceClientFailsWithoutAnyData(t *testing.T) {
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: &fakeBookClient{err: errors.New("magpi is down")}},
+		Source{Name: "nas", Client: &fakeBookClient{err: errors.New("nas is down")}},
+	)
+
+	_, err := subject.GetBooks(t.Context())
+	assert.ErrorContains(t, err, "magpi is down")
+	assert.ErrorContains(t, err, "nas is down")
+}
+
+func TestMultiSourceClientFailsWhenEverySourceFails(t *testing.T) {
+	magpi := &fakeBookClient{books: []entities.Book{{Title: "Issue 1", Category: "MagPI"}}}
+	nas := &fakeBookClient{err: errors.New("nas is down")}
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: magpi},
+		Source{Name: "nas", Client: nas},
+	)
+
+	_, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+
+	magpi.books, magpi.err = nil, errors.New("magpi is down")
+	books, err := subject.GetBooks(t.Context())
+	assert.Nil(t, books)
+	var stale *StaleError
+	require.ErrorAs(t, err, &stale)
+	assert.ErrorContains(t, err, "magpi is down")
+	assert.ErrorContains(t, err, "nas is down")
+	assert.Equal(t, []string{"MagPI:Issue 1"}, categoryTitles(stale.Books), "the last good books are attached")
+}
+
+func TestMultiSourceClientPartialFirstRefresh(t *testing.T) {
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: &fakeBookClient{err: errors.New("magpi is down")}},
+		Source{Name: "nas", Client: &fakeBookClient{books: []entities.Book{{Title: "Training", Category: "Docs"}}}},
+	)
+
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Docs:Training"}, categoryTitles(books))
+}
+
+func TestMultiSourceClientRestoresPreviousCatalog(t *testing.T) {
+	nas := &fakeBookClient{err: errors.New("nas is down")}
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: &fakeBookClient{books: []entities.Book{{Title: "Issue 2", Category: "MagPI"}}}},
+		Source{Name: "nas", Namespace: "NAS", Client: nas},
+	)
+	subject.Restore([]entities.Book{
+		{Title: "Issue 1", Category: "MagPI", Source: "magpi"},
+		{Title: "Training", Category: "NAS/Docs", Source: "nas"},
+		{Title: "Unknown", Category: "Docs", Source: "removed"},
+	})
+
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MagPI:Issue 2", "NAS/Docs:Training"}, categoryTitles(books),
+		"refreshed sources replace the restored books")
+	assert.Equal(t, "magpi", books[0].Source)
+}
+
+func TestMultiSourceClientUnchanged(t *testing.T) {
+	magpi := &fakeBookClient{books: []entities.Book{{Title: "Issue 1", Category: "MagPI"}}}
+	nas := &fakeBookClient{books: []entities.Book{{Title: "Training", Category: "Docs"}}}
+	subject := NewMultiSourceClient(
+		Source{Name: "magpi", Client: magpi},
+		Source{Name: "nas", Client: nas},
+	)
+
+	_, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+
+	magpi.books, magpi.err = nil, entities.ErrUnchanged
+	nas.err = errors.New("nas is down")
+	_, err = subject.GetBooks(t.Context())
+	assert.ErrorIs(t, err, entities.ErrUnchanged, "no source returned new books")
+
+	health, err := subject.Health(t.Context())
+	require.NoError(t, err)
+	assert.True(t, health[0].Healthy)
+	assert.Equal(t, 1, health[0].Books)
+	assert.False(t, health[1].Healthy)
+
+	nas.books, nas.err = []entities.Book{{Title: "Advanced", Category: "Docs"}}, nil
+	books, err := subject.GetBooks(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MagPI:Issue 1", "Docs:Advanced"}, categoryTitles(books),
+		"unchanged sources keep their books")
+}
@@ -2,99 +2,173 @@ package bookshelf
 
 import (
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
-	"errors"
-	"fmt"
-	"io"
 	"log/slog"
-	"maps"
+	"slices"
 	"sort"
+	"sync/atomic"
+	"time"
 
 	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/search"
 )
 
 // Storage is an in-memory storage for books.
+// The books are kept in an immutable catalog snapshot that is atomically
+// swapped on every ReplaceAll, so readers always observe a consistent
+// version of the catalog and never wait for a refresh to finish.
 type Storage struct {
+	catalog atomic.Pointer[catalog]
+}
+
+// catalog is an immutable snapshot of the stored books.
+// Once published it must never be modified, a new one is built instead.
+type catalog struct {
 	books           []entities.Book
 	bookIDMap       map[string]*entities.Book
 	bookCategoryMap map[string][]entities.Book
+	categories      []string
+	index           *search.Index
+	// aliases maps the former IDs of books to their current ID.
+	aliases map[string]string
 }
 
 // NewStorage creates a new instance of Storage.
 func NewStorage() *Storage {
-	return &Storage{
-		books:           []entities.Book{},
-		bookIDMap:       map[string]*entities.Book{},
-		bookCategoryMap: map[string][]entities.Book{},
+	s := &Storage{}
+	s.catalog.Store(newCatalog(nil, nil))
+	return s
+}
+
+// newCatalog builds a catalog snapshot from books that already have an ID.
+func newCatalog(books []entities.Book, aliases map[string]string) *catalog {
+	c := &catalog{
+		books:           make([]entities.Book, 0, len(books)),
+		bookIDMap:       make(map[string]*entities.Book, len(books)),
+		bookCategoryMap: make(map[string][]entities.Book),
+		categories:      []string{},
+		aliases:         aliases,
 	}
+
+	c.books = append(c.books, books...)
+	docs := make([]search.Document, 0, len(c.books))
+	for i := range c.books {
+		book := &c.books[i]
+		c.bookIDMap[book.ID] = book
+		if _, ok := c.bookCategoryMap[book.Category]; !ok {
+			c.categories = append(c.categories, book.Category)
+		}
+		c.bookCategoryMap[book.Category] = append(c.bookCategoryMap[book.Category], *book)
+		docs = append(docs, search.Document{Title: book.Title, Description: book.Description})
+	}
+	sort.Strings(c.categories)
+	c.index = search.NewIndex(docs)
+
+	return c
 }
 
 // GetByID retrieves a book by its ID.
+// The returned book is a copy, changing it does not affect the storage.
 func (s *Storage) GetByID(ctx context.Context, id string) (*entities.Book, error) {
-	book := s.bookIDMap[id]
-	return book, nil
+	book, ok := s.catalog.Load().bookIDMap[id]
+	if !ok {
+		return nil, nil
+	}
+	result := *book
+	return &result, nil
+}
+
+// ResolveAlias returns the current ID of a book that was known under
+// another ID.
+func (s *Storage) ResolveAlias(ctx context.Context, id string) (string, bool) {
+	current, ok := s.catalog.Load().aliases[id]
+	return current, ok
 }
 
-// Get retrieves books, optionally filtered by category.
-func (s *Storage) Get(ctx context.Context, category string) ([]entities.Book, error) {
-	if len(category) > 0 {
-		slog.Debug("getting books in category", slog.String("category", category))
-		return s.bookCategoryMap[category], nil
+// Get retrieves the books selected, sorted and paged by the query.
+// The books of the returned page belong to the current snapshot and must
+// not be modified.
+func (s *Storage) Get(ctx context.Context, q entities.BookQuery) (entities.BookPage, error) {
+	c := s.catalog.Load()
+	books := c.books
+	if len(q.Category) > 0 {
+		slog.Debug("getting books in category", slog.String("category", q.Category))
+		books = c.bookCategoryMap[q.Category]
+		q.Category = ""
+	}
+	return queryBooks(books, q)
+}
+
+// Search retrieves the books matching the text, best match first unless
+// the query sorts them otherwise. The books are selected and paged by the
+// query like with Get.
+func (s *Storage) Search(ctx context.Context, text string, q entities.BookQuery) (entities.BookPage, error) {
+	c := s.catalog.Load()
+	slog.Debug("searching books", slog.String("category", q.Category), slog.String("query", text))
+
+	result := []entities.Book{}
+	for _, r := range c.index.Search(text) {
+		book := c.books[r.Doc]
+		if q.Category == "" || book.Category == q.Category {
+			result = append(result, book)
+		}
 	}
-	return s.books, nil
+	q.Category = ""
+	return queryBooks(result, q)
 }
 
 // GetCategories retrieves all book categories.
 func (s *Storage) GetCategories(ctx context.Context) ([]string, error) {
-	result := []string{}
-	categories := maps.Keys(s.bookCategoryMap)
-	for c := range categories {
-		result = append(result, c)
-	}
-	sort.StringSlice(result).Sort()
+	categories := s.catalog.Load().categories
+	result := make([]string, len(categories))
+	copy(result, categories)
 	return result, nil
 }
 
 // ReplaceAll replaces all books in storage with the provided list.
+// The new catalog is built aside and published in a single atomic step.
 func (s *Storage) ReplaceAll(ctx context.Context, books []entities.Book) error {
-	slog.Debug("replacing books", slog.Int("size", len(books)))
-	bookSlice := make([]entities.Book, 0, len(books))
-	bookIDMap := make(map[string]*entities.Book)
-	bookCategoryMap := make(map[string][]entities.Book)
-
-	for _, book := range books {
-		if err := s.genBookID(ctx, &book); err != nil {
-			return fmt.Errorf("error generating id: %w", err)
-		}
+	s.catalog.Store(s.buildCatalog(ctx, books))
+	return nil
+}
 
-		bookSlice = append(bookSlice, book)
-		bookIDMap[book.ID] = &book
-		bookCategoryMap[book.Category] = append(bookCategoryMap[book.Category], book)
-	}
+// buildCatalog assigns IDs to the books and builds a new catalog snapshot
+// without publishing it. The aliases of the current catalog are carried
+// over, with the books that changed ID since.
+func (s *Storage) buildCatalog(_ context.Context, books []entities.Book) *catalog {
+	slog.Debug("replacing books", slog.Int("size", len(books)))
+	bookSlice := slices.Clone(books)
+	assignIDs(bookSlice)
 
-	s.books = bookSlice
-	s.bookCategoryMap = bookCategoryMap
-	s.bookIDMap = bookIDMap
-	return nil
+	previous := s.catalog.Load()
+	aliases := updateAliases(previous.aliases, previous.books, bookSlice)
+	setAdded(bookSlice, previous.books, aliases, time.Now().UTC())
+	return newCatalog(bookSlice, aliases)
 }
 
-// genBookID generates a unique ID for the book if it doesn't already have one.
-func (s *Storage) genBookID(_ context.Context, book *entities.Book) error {
-	if book == nil {
-		return errors.New("book is required")
+// setAdded sets when the books were first seen: the time they were added to
+// the previous catalog, under their current or a former ID, or else now.
+// The books that already know it, such as the restored ones, keep it.
+func setAdded(books, previous []entities.Book, aliases map[string]string, now time.Time) {
+	added := make(map[string]time.Time, len(previous))
+	for _, b := range previous {
+		added[b.ID] = b.Added
 	}
-
-	if book.ID != "" {
-		slog.Info("book already had an id", slog.String("bookID", book.ID))
-		return nil
+	for from, to := range aliases {
+		if t, ok := added[from]; ok {
+			if _, ok := added[to]; !ok {
+				added[to] = t
+			}
+		}
 	}
 
-	hash := sha1.New()
-	_, err := io.WriteString(hash, fmt.Sprintf("%s:%s", book.Cover, book.Title))
-	if err != nil {
-		return err
+	for i := range books {
+		if !books[i].Added.IsZero() {
+			continue
+		}
+		if t := added[books[i].ID]; !t.IsZero() {
+			books[i].Added = t
+		} else {
+			books[i].Added = now
+		}
 	}
-	book.ID = hex.EncodeToString(hash.Sum(nil))
-	return nil
 }
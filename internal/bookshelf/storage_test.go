@@ -0,0 +1,182 @@
+package bookshelf
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generation builds a catalog where every book title carries the generation
+// number, so readers can detect if they observed a mix of two versions.
+func generation(n int) []entities.Book {
+	books := make([]entities.Book, 0, 6)
+	for i := range 6 {
+		category := "MagPI"
+		if i%2 == 0 {
+			category = "Book"
+		}
+		books = append(books, entities.Book{
+			Title:        fmt.Sprintf("gen-%d", n),
+			Cover:        fmt.Sprintf("http://localhost/covers/%d", i),
+			Category:     category,
+			Availability: entities.AvailabilityLocked,
+			Year:         2000 + n,
+			Month:        time.Month(i + 1),
+			Edition:      i,
+		})
+	}
+	return books
+}
+
+func TestStorageReplaceAll(t *testing.T) {
+	subject := NewStorage()
+
+	page, err := subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Books)
+
+	categories, err := subject.GetCategories(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, categories)
+
+	require.NoError(t, subject.ReplaceAll(t.Context(), generation(1)))
+
+	page, err = subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Books, 6)
+	assert.Equal(t, 6, page.Total)
+
+	magPi, err := subject.Get(t.Context(), entities.BookQuery{Category: "MagPI"})
+	require.NoError(t, err)
+	assert.Len(t, magPi.Books, 3)
+
+	categories, err = subject.GetCategories(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Book", "MagPI"}, categories)
+
+	for _, b := range page.Books {
+		require.NotEmpty(t, b.ID)
+		found, err := subject.GetByID(t.Context(), b.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, b, *found)
+	}
+
+	missing, err := subject.GetByID(t.Context(), "missing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestStorageGetByIDReturnsCopy(t *testing.T) {
+	subject := NewStorage()
+	require.NoError(t, subject.ReplaceAll(t.Context(), generation(1)))
+
+	page, err := subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	books := page.Books
+
+	found, err := subject.GetByID(t.Context(), books[0].ID)
+	require.NoError(t, err)
+	found.Title = "changed"
+
+	again, err := subject.GetByID(t.Context(), books[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "gen-1", again.Title)
+}
+
+// TestStorageConcurrentReadsDuringReplace hammers every read method while the
+// catalog is being replaced. Run it with -race to catch unsynchronized access.
+func TestStorageConcurrentReadsDuringReplace(t *testing.T) {
+	const (
+		readers      = 8
+		replacements = 200
+	)
+
+	subject := NewStorage()
+	require.NoError(t, subject.ReplaceAll(t.Context(), generation(0)))
+
+	var (
+		done     atomic.Bool
+		wg       sync.WaitGroup
+		failures atomic.Int64
+	)
+
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				page, _ := subject.Get(t.Context(), entities.BookQuery{})
+				books := page.Books
+				if len(books) != 6 {
+					failures.Add(1)
+					continue
+				}
+				// every book of a snapshot must belong to the same generation
+				for _, b := range books {
+					if b.Title != books[0].Title {
+						failures.Add(1)
+					}
+				}
+
+				book, _ := subject.GetByID(t.Context(), books[0].ID)
+				// the generation may have been replaced meanwhile, but an ID
+				// never resolves to a book from a different generation
+				if book != nil && book.Title != books[0].Title {
+					failures.Add(1)
+				}
+
+				inCategory, _ := subject.Get(t.Context(), entities.BookQuery{Category: "Book"})
+				if len(inCategory.Books) != 3 {
+					failures.Add(1)
+				}
+
+				categories, _ := subject.GetCategories(t.Context())
+				if len(categories) != 2 {
+					failures.Add(1)
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= replacements; i++ {
+		require.NoError(t, subject.ReplaceAll(t.Context(), generation(i)))
+	}
+	done.Store(true)
+	wg.Wait()
+
+	assert.Zero(t, failures.Load(), "readers observed an inconsistent catalog")
+
+	page, err := subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("gen-%d", replacements), page.Books[0].Title)
+}
+
+func TestStorageKeepsAddedTime(t *testing.T) {
+	subject := NewStorage()
+	first := []entities.Book{{Title: "The MagPi 150", File: "MagPi150.pdf", Category: "MagPI"}}
+	require.NoError(t, subject.ReplaceAll(t.Context(), first))
+	page, err := subject.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	added := page.Books[0].Added
+	require.False(t, added.IsZero())
+
+	// the file is renamed, and a new issue is published
+	second := []entities.Book{
+		{Title: "The MagPi 150", File: "MagPi-150.pdf", Category: "MagPI"},
+		{Title: "The MagPi 151", File: "MagPi151.pdf", Category: "MagPI"},
+	}
+	require.NoError(t, subject.ReplaceAll(t.Context(), second))
+	page, err = subject.Get(t.Context(), entities.BookQuery{Sort: entities.SortAdded})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"magpi151", "magpi-150"}, ids(page.Books))
+	assert.Equal(t, added, page.Books[1].Added, "a renamed book keeps the time it was added")
+	assert.False(t, page.Books[0].Added.Before(added))
+}
@@ -2,7 +2,11 @@ package bookshelf
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
@@ -10,35 +14,84 @@ import (
 
 // BookClient defines the interface for fetching books from an external source.
 // It is used by the BookshelfUpdater to get the latest book data.
+// GetBooks returns entities.ErrUnchanged when the source reports that nothing changed
+// since the previous call, so the catalog is left as is.
 type BookClient interface {
 	GetBooks(ctx context.Context) ([]entities.Book, error)
 }
 
 // BookReferenceStorage defines the interface for storing book references.
 // It is used by the BookshelfUpdater to update the stored book data.
+// ResolveAlias returns the current ID of a book whose ID changed.
 type BookReferenceStorage interface {
+	Get(ctx context.Context, q entities.BookQuery) (entities.BookPage, error)
 	ReplaceAll(ctx context.Context, books []entities.Book) error
+	ResolveAlias(ctx context.Context, id string) (string, bool)
+}
+
+// ChangeRecorder defines the interface for recording catalog changes.
+// It is used by the BookshelfUpdater to keep track of what each refresh changed.
+type ChangeRecorder interface {
+	Record(ctx context.Context, changes []entities.Change) error
+}
+
+// Schedule defines when the regular refreshes run.
+type Schedule interface {
+	// Next returns the first time strictly after t at which a refresh runs.
+	Next(t time.Time) time.Time
 }
 
 // BookshelfUpdater is responsible for periodically updating the bookshelf
 // by fetching new book data from a BookClient and storing it in a BookReferenceStorage.
+// A failed refresh is retried before the next regular refresh, following
+// its Backoff.
 type BookshelfUpdater struct {
 	bookClient BookClient
 	storage    BookReferenceStorage
-	interval   time.Duration
+	changes    ChangeRecorder
+	schedule   Schedule
+	backoff    Backoff
+	refresh    chan struct{}
+
+	clock  Clock
+	random func() float64
+	// failures is the number of consecutive failed refreshes.
+	failures int
+
+	mu     sync.RWMutex
+	status entities.UpdaterStatus
 }
 
 // NewBookshelfUpdater creates a new instance of BookshelfUpdater.
-// It takes a BookClient, a BookReferenceStorage, and an update interval as parameters.
+// It takes a BookClient, a BookReferenceStorage, a ChangeRecorder, the
+// Schedule of the regular refreshes and the Backoff used to retry failed
+// refreshes as parameters.
 func NewBookshelfUpdater(
 	bookClient BookClient,
 	storage BookReferenceStorage,
-	interval time.Duration,
+	changes ChangeRecorder,
+	schedule Schedule,
+	backoff Backoff,
 ) *BookshelfUpdater {
 	return &BookshelfUpdater{
 		bookClient: bookClient,
 		storage:    storage,
-		interval:   interval,
+		changes:    changes,
+		schedule:   schedule,
+		backoff:    backoff,
+		refresh:    make(chan struct{}, 1),
+		clock:      systemClock{},
+		random:     rand.Float64,
+	}
+}
+
+// Refresh asks the running updater to refresh the catalog now instead of
+// waiting for the next scheduled one. It does not block, concurrent requests and
+// requests made while a refresh is already pending are merged into it.
+func (u *BookshelfUpdater) Refresh() {
+	select {
+	case u.refresh <- struct{}{}:
+	default:
 	}
 }
 
@@ -47,20 +100,162 @@ func NewBookshelfUpdater(
 // This operation is blocking, you might want to run it in a separate goroutine.
 func (u *BookshelfUpdater) Run(ctx context.Context) error {
 	slog.Debug("starting the updater")
+	var delay time.Duration
+	u.scheduled(delay)
+
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Debug("context is done, exiting the bookshelf updater")
 			return nil
-		default:
-			books, err := u.bookClient.GetBooks(ctx)
-			if err != nil {
-				slog.ErrorContext(ctx, "failed to get books", slog.Any("error", err))
-			} else if err := u.storage.ReplaceAll(ctx, books); err != nil {
-				slog.ErrorContext(ctx, "failed to update books", slog.Any("error", err))
-			}
-			slog.Debug("updater got new books, sleeping", slog.Any("interval", u.interval))
+		case <-u.clock.After(delay):
+		case <-u.refresh:
+			slog.Debug("refresh requested, updating the bookshelf")
+		}
+
+		u.mu.Lock()
+		u.status.Running = true
+		u.status.LastAttempt = u.clock.Now().UTC()
+		u.mu.Unlock()
+
+		err := u.update(ctx)
+		delay = u.nextDelay(ctx, err)
+		u.finished(err, delay)
+	}
+}
+
+// Status returns the status of the refreshes and counts the current catalog.
+func (u *BookshelfUpdater) Status(ctx context.Context) (entities.UpdaterStatus, error) {
+	u.mu.RLock()
+	status := u.status
+	u.mu.RUnlock()
+
+	catalog, err := u.storage.Get(ctx, entities.BookQuery{})
+	if err != nil {
+		return entities.UpdaterStatus{}, err
+	}
+	categories := map[string]bool{}
+	for _, b := range catalog.Books {
+		categories[b.Category] = true
+		if b.IsLocked() {
+			status.Locked++
 		}
-		time.Sleep(u.interval)
 	}
+	status.Books = catalog.Total
+	status.Categories = len(categories)
+	return status, nil
+}
+
+// scheduled records when the next refresh runs.
+func (u *BookshelfUpdater) scheduled(delay time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.status.NextRun = u.clock.Now().Add(delay).UTC()
+}
+
+// finished records the result of a refresh and when the next one runs.
+func (u *BookshelfUpdater) finished(err error, delay time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := u.clock.Now().UTC()
+	u.status.Running = false
+	u.status.Failures = u.failures
+	u.status.NextRun = now.Add(delay)
+	if err != nil {
+		u.status.LastError = err.Error()
+		u.status.LastErrorAt = now
+	} else {
+		u.status.LastSuccess = now
+	}
+}
+
+// nextDelay returns the time to wait before the next refresh, given the
+// result of the last one. Failures are retried following the backoff, but
+// never later than the next scheduled refresh. A success resets the backoff.
+func (u *BookshelfUpdater) nextDelay(ctx context.Context, err error) time.Duration {
+	now := u.clock.Now()
+	scheduled := u.schedule.Next(now).Sub(now)
+	if err == nil {
+		u.failures = 0
+		slog.Debug("updater got new books, sleeping", slog.Duration("delay", scheduled))
+		return scheduled
+	}
+
+	u.failures++
+	delay := min(u.backoff.Delay(u.failures, u.random()), scheduled)
+	slog.WarnContext(ctx, "refresh failed, retrying",
+		slog.Int("failures", u.failures),
+		slog.Duration("delay", delay),
+	)
+	return delay
+}
+
+// update fetches the books, replaces the stored catalog and records what
+// changed since the previous one.
+// It returns an error when the books cannot be fetched or stored, a
+// failure to record the changes is only logged.
+func (u *BookshelfUpdater) update(ctx context.Context) error {
+	books, err := u.bookClient.GetBooks(ctx)
+	if errors.Is(err, entities.ErrUnchanged) {
+		slog.DebugContext(ctx, "books are unchanged, keeping the current catalog")
+		return nil
+	}
+	var stale *StaleError
+	if errors.As(err, &stale) {
+		// the stored catalog already holds the last good books
+		slog.ErrorContext(ctx, "every source failed, keeping the current catalog",
+			slog.Int("lastGoodBooks", len(stale.Books)),
+			slog.Any("error", stale.Err),
+		)
+		return err
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get books", slog.Any("error", err))
+		return err
+	}
+
+	before, err := u.storage.Get(ctx, entities.BookQuery{})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get the current books", slog.Any("error", err))
+		return err
+	}
+	previous := before.Books
+
+	// the catalog might have been replaced even if an error is returned,
+	// the changes are computed from what is actually stored
+	replaceErr := u.storage.ReplaceAll(ctx, books)
+	if replaceErr != nil {
+		slog.ErrorContext(ctx, "failed to update books", slog.Any("error", replaceErr))
+	}
+
+	after, err := u.storage.Get(ctx, entities.BookQuery{})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get the updated books", slog.Any("error", err))
+		return errors.Join(replaceErr, err)
+	}
+	current := after.Books
+
+	if len(previous) == 0 {
+		// every book of the first catalog would be reported as added
+		return replaceErr
+	}
+
+	// the books that changed ID are the same books, not removed and added ones
+	renamed := slices.Clone(previous)
+	for i, b := range renamed {
+		if id, ok := u.storage.ResolveAlias(ctx, b.ID); ok {
+			renamed[i].ID = id
+		}
+	}
+
+	changes := Diff(renamed, current, u.clock.Now().UTC())
+	if len(changes) == 0 {
+		return replaceErr
+	}
+	slog.InfoContext(ctx, "catalog changed", slog.Int("changes", len(changes)))
+	if err := u.changes.Record(ctx, changes); err != nil {
+		slog.ErrorContext(ctx, "failed to record catalog changes", slog.Any("error", err))
+	}
+	return replaceErr
 }
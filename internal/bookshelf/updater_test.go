@@ -0,0 +1,353 @@
+package bookshelf
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testBackoff = Backoff{Initial: time.Minute, Max: 10 * time.Minute, Multiplier: 2, Jitter: 0.2}
+
+type fakeBookClient struct {
+	calls atomic.Int64
+	books []entities.Book
+	err   error
+}
+
+func (c *fakeBookClient) GetBooks(ctx context.Context) ([]entities.Book, error) {
+	c.calls.Add(1)
+	return c.books, c.err
+}
+
+func TestUpdaterStopsWhenContextIsDone(t *testing.T) {
+	client := &fakeBookClient{books: generation(1)}
+	storage := NewStorage()
+	subject := NewBookshelfUpdater(client, storage, NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- subject.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		page, _ := storage.Get(t.Context(), entities.BookQuery{})
+		return len(page.Books) == 6
+	}, time.Second, time.Millisecond, "the first refresh must run immediately")
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("updater did not stop after the context was cancelled")
+	}
+	assert.Equal(t, int64(1), client.calls.Load())
+}
+
+func TestUpdaterRecordsChanges(t *testing.T) {
+	client := &fakeBookClient{books: []entities.Book{
+		{Title: "Issue 1", Link: "http://localhost/1.pdf", Category: "MagPI"},
+		{Title: "Issue 2", Category: "MagPI"},
+		{Title: "Old Book", Link: "http://localhost/old.pdf", Category: "Book"},
+	}}
+	storage := NewStorage()
+	changes := NewChangeLog(t.TempDir())
+	subject := NewBookshelfUpdater(client, storage, changes, schedule.Every(time.Hour), testBackoff)
+
+	subject.update(t.Context())
+	recorded, err := changes.Recent(t.Context(), "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, recorded, "the first catalog is not a change")
+
+	client.books = []entities.Book{
+		{Title: "Issue 1", Link: "http://localhost/1.pdf", Category: "MagPI"},
+		{Title: "Issue 2", Link: "http://localhost/2.pdf", Category: "MagPI"},
+		{Title: "Issue 3", Category: "MagPI"},
+	}
+	subject.update(t.Context())
+
+	recorded, err = changes.Recent(t.Context(), "", 0)
+	require.NoError(t, err)
+	got := map[string]entities.ChangeKind{}
+	for _, c := range recorded {
+		got[c.Book.Title] = c.Kind
+		assert.False(t, c.At.IsZero())
+	}
+	assert.Equal(t, map[string]entities.ChangeKind{
+		"Issue 2":  entities.ChangeUnlocked,
+		"Issue 3":  entities.ChangeAdded,
+		"Old Book": entities.ChangeRemoved,
+	}, got)
+}
+
+func TestUpdaterRefresh(t *testing.T) {
+	client := &fakeBookClient{books: generation(1)}
+	subject := NewBookshelfUpdater(client, NewStorage(), NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go func() {
+		_ = subject.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return client.calls.Load() == 1
+	}, time.Second, time.Millisecond, "the first refresh must run immediately")
+
+	subject.Refresh()
+	require.Eventually(t, func() bool {
+		return client.calls.Load() == 2
+	}, time.Second, time.Millisecond, "the refresh must not wait for the interval")
+}
+
+func TestUpdaterRefreshRequestsAreMerged(t *testing.T) {
+	subject := NewBookshelfUpdater(&fakeBookClient{}, NewStorage(), NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+
+	subject.Refresh()
+	subject.Refresh()
+	subject.Refresh()
+	assert.Len(t, subject.refresh, 1)
+}
+
+func TestUpdaterKeepsCatalogWhenUnchanged(t *testing.T) {
+	client := &fakeBookClient{books: generation(1)}
+	storage := &countingStorage{Storage: NewStorage()}
+	subject := NewBookshelfUpdater(client, storage, NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+
+	subject.update(t.Context())
+	require.Equal(t, 1, storage.replaced)
+
+	client.books, client.err = nil, entities.ErrUnchanged
+	subject.update(t.Context())
+	assert.Equal(t, 1, storage.replaced, "an unchanged catalog is not replaced")
+
+	page, err := storage.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Books, 6)
+}
+
+// countingStorage counts the catalog replacements.
+type countingStorage struct {
+	*Storage
+	replaced int
+}
+
+func (s *countingStorage) ReplaceAll(ctx context.Context, books []entities.Book) error {
+	s.replaced++
+	return s.Storage.ReplaceAll(ctx, books)
+}
+
+func TestUpdaterFailsWhenCatalogCannotBeStored(t *testing.T) {
+	client := &fakeBookClient{books: generation(1)}
+	storage := &failingStorage{Storage: NewStorage(), err: errors.New("disk full")}
+	subject := NewBookshelfUpdater(client, storage, NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+	subject.random = func() float64 { return 0.5 }
+
+	err := subject.update(t.Context())
+	require.ErrorIs(t, err, storage.err)
+	subject.finished(err, subject.nextDelay(t.Context(), err))
+
+	status, err := subject.Status(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Failures)
+	assert.Equal(t, "disk full", status.LastError)
+}
+
+// failingStorage fails to replace the catalog.
+type failingStorage struct {
+	*Storage
+	err error
+}
+
+func (s *failingStorage) ReplaceAll(context.Context, []entities.Book) error {
+	return s.err
+}
+
+// fakeClock lets the tests decide when the updater stops waiting.
+// Every wait is reported on waits and ends when a time is sent on fire.
+type fakeClock struct {
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		waits: make(chan time.Duration, 16),
+		fire:  make(chan time.Time),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.fire
+}
+
+// next waits for the updater to wait, and returns for how long it asked to.
+func (c *fakeClock) next(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("the updater is not waiting")
+		return 0
+	}
+}
+
+func TestUpdaterRetriesWithBackoff(t *testing.T) {
+	client := &fakeBookClient{err: errors.New("no such host")}
+	subject := NewBookshelfUpdater(client, NewStorage(), NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+	clock := newFakeClock()
+	subject.clock = clock
+	// the middle of the jitter range, so delays are exact
+	subject.random = func() float64 { return 0.5 }
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go func() {
+		_ = subject.Run(ctx)
+	}()
+
+	assert.Zero(t, clock.next(t), "the first refresh runs immediately")
+
+	var delays []time.Duration
+	for range 6 {
+		clock.fire <- time.Time{}
+		delays = append(delays, clock.next(t))
+	}
+	assert.Equal(t, []time.Duration{
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		10 * time.Minute,
+		10 * time.Minute,
+	}, delays, "delays double up to the cap")
+
+	client.err = nil
+	clock.fire <- time.Time{}
+	assert.Equal(t, time.Hour, clock.next(t), "a success waits for the interval")
+
+	client.err = errors.New("no such host")
+	clock.fire <- time.Time{}
+	assert.Equal(t, time.Minute, clock.next(t), "a success resets the backoff")
+}
+
+func TestUpdaterRetryNeverExceedsSchedule(t *testing.T) {
+	subject := NewBookshelfUpdater(&fakeBookClient{}, NewStorage(), NewChangeLog(t.TempDir()), schedule.Every(3*time.Minute), testBackoff)
+	subject.random = func() float64 { return 0.5 }
+
+	assert.Equal(t, time.Minute, subject.nextDelay(t.Context(), errors.New("down")))
+	assert.Equal(t, 2*time.Minute, subject.nextDelay(t.Context(), errors.New("down")))
+	assert.Equal(t, 3*time.Minute, subject.nextDelay(t.Context(), errors.New("down")))
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Initial: time.Minute, Max: 10 * time.Minute, Multiplier: 3, Jitter: 0.5}
+
+	assert.Equal(t, time.Minute, backoff.Delay(1, 0.5))
+	assert.Equal(t, 3*time.Minute, backoff.Delay(2, 0.5))
+	assert.Equal(t, 9*time.Minute, backoff.Delay(3, 0.5))
+	assert.Equal(t, 10*time.Minute, backoff.Delay(4, 0.5))
+	assert.Equal(t, 10*time.Minute, backoff.Delay(100, 0.5), "large attempts do not overflow")
+
+	assert.Equal(t, 30*time.Second, backoff.Delay(1, 0), "jitter removes up to half the delay")
+	assert.Equal(t, 90*time.Second, backoff.Delay(1, 1), "jitter adds up to half the delay")
+	assert.Equal(t, 10*time.Minute, backoff.Delay(4, 1), "jitter never exceeds the cap")
+	assert.Equal(t, 5*time.Minute, backoff.Delay(4, 0))
+}
+
+func TestUpdaterStatus(t *testing.T) {
+	client := &fakeBookClient{books: generation(1)}
+	subject := NewBookshelfUpdater(client, NewStorage(), NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+	clock := newFakeClock()
+	subject.clock = clock
+	subject.random = func() float64 { return 0.5 }
+	now := clock.Now()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go func() {
+		_ = subject.Run(ctx)
+	}()
+
+	clock.next(t)
+	status, err := subject.Status(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, entities.UpdaterStatus{NextRun: now}, status, "the first refresh is scheduled right away")
+
+	clock.fire <- time.Time{}
+	clock.next(t)
+	status, err = subject.Status(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, entities.UpdaterStatus{
+		LastAttempt: now,
+		LastSuccess: now,
+		NextRun:     now.Add(time.Hour),
+		Books:       6,
+		Locked:      6,
+		Categories:  2,
+	}, status)
+
+	client.err = errors.New("no such host")
+	clock.fire <- time.Time{}
+	clock.next(t)
+	status, err = subject.Status(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, entities.UpdaterStatus{
+		LastAttempt: now,
+		LastSuccess: now,
+		LastError:   "no such host",
+		LastErrorAt: now,
+		Failures:    1,
+		NextRun:     now.Add(time.Minute),
+		Books:       6,
+		Locked:      6,
+		Categories:  2,
+	}, status, "the catalog is kept after a failure")
+}
+
+func TestUpdaterCountsFailureOfEverySource(t *testing.T) {
+	magpi := &fakeBookClient{books: generation(1)}
+	client := NewMultiSourceClient(Source{Name: "magpi", Client: magpi})
+	storage := NewStorage()
+	subject := NewBookshelfUpdater(client, storage, NewChangeLog(t.TempDir()), schedule.Every(time.Hour), testBackoff)
+	subject.random = func() float64 { return 0.5 }
+
+	require.NoError(t, subject.update(t.Context()))
+
+	magpi.books, magpi.err = nil, errors.New("no such host")
+	err := subject.update(t.Context())
+	require.Error(t, err, "the refresh failed even though the last good books are known")
+	subject.finished(err, subject.nextDelay(t.Context(), err))
+
+	status, err := subject.Status(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Failures)
+	assert.Contains(t, status.LastError, "no such host")
+	assert.Equal(t, 6, status.Books, "the last good books are still served")
+}
+
+func TestUpdaterFollowsSchedule(t *testing.T) {
+	daily, err := schedule.Parse("0 3 * * *", time.UTC)
+	require.NoError(t, err)
+	subject := NewBookshelfUpdater(&fakeBookClient{}, NewStorage(), NewChangeLog(t.TempDir()), daily, testBackoff)
+	// midnight, three hours before the scheduled refresh
+	subject.clock = newFakeClock()
+	subject.random = func() float64 { return 0.5 }
+
+	assert.Equal(t, 3*time.Hour, subject.nextDelay(t.Context(), nil))
+	assert.Equal(t, time.Minute, subject.nextDelay(t.Context(), errors.New("down")))
+}
@@ -0,0 +1,640 @@
+// Package config loads the application configuration.
+//
+// Every setting has a default value and can be overridden, from lowest to
+// highest precedence, by:
+//
+//  1. a YAML configuration file, given by the -config flag or the
+//     BOOKSHELF_CONFIG environment variable;
+//  2. BOOKSHELF_* environment variables;
+//  3. command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/schedule"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the configuration for every component of the application.
+type Config struct {
+	Server  ServerConfig   `yaml:"server"`
+	Storage StorageConfig  `yaml:"storage"`
+	Updater UpdaterConfig  `yaml:"updater"`
+	MagPi   MagPiConfig    `yaml:"magpi"`
+	Sources []SourceConfig `yaml:"sources"`
+	Mirror  MirrorConfig   `yaml:"mirror"`
+	Covers  CoversConfig   `yaml:"covers"`
+	Admin   AdminConfig    `yaml:"admin"`
+	Log     LogConfig      `yaml:"log"`
+}
+
+// ServerConfig holds the configuration of the HTTP server.
+type ServerConfig struct {
+	// Address is the TCP address the HTTP server listens on.
+	Address string `yaml:"address"`
+	// ShutdownTimeout is the maximum time given to in-flight requests to
+	// complete when the server is shutting down.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// StorageConfig holds the configuration of the book storage.
+type StorageConfig struct {
+	// DataDir is the directory where the catalog is persisted.
+	DataDir string `yaml:"dataDir"`
+}
+
+// UpdaterConfig holds the configuration of the bookshelf updater.
+type UpdaterConfig struct {
+	// Interval is the time between two catalog refreshes.
+	// It is ignored when a schedule is set.
+	Interval time.Duration `yaml:"interval"`
+	// Schedule is a cron expression telling when the catalog is refreshed,
+	// several expressions are separated by semicolons. See package schedule.
+	Schedule string `yaml:"schedule"`
+	// Timezone is the IANA name of the timezone of the schedule, such as
+	// Europe/London. When empty, the local timezone is used.
+	Timezone string `yaml:"timezone"`
+	// Retry is the policy used to retry a failed refresh.
+	Retry RetryConfig `yaml:"retry"`
+	// StaleAfter is the age of the last successful refresh after which the
+	// readiness probe reports the catalog as degraded. Zero disables it.
+	StaleAfter time.Duration `yaml:"staleAfter"`
+}
+
+// RetryConfig holds the exponential backoff used to retry a failed refresh.
+// A retry is never scheduled later than the regular refresh.
+type RetryConfig struct {
+	// InitialInterval is the delay after the first failure.
+	InitialInterval time.Duration `yaml:"initialInterval"`
+	// MaxInterval caps the delay between two retries.
+	MaxInterval time.Duration `yaml:"maxInterval"`
+	// Multiplier is the growth of the delay after each failure.
+	Multiplier float64 `yaml:"multiplier"`
+	// Jitter is the fraction of the delay randomly added or removed,
+	// between 0 and 1.
+	Jitter float64 `yaml:"jitter"`
+}
+
+// MagPiConfig holds the configuration of the MagPi source.
+type MagPiConfig struct {
+	// URL is the location of the MagPi bookshelf XML.
+	URL string `yaml:"url"`
+	// Timeout is the maximum duration of a request to the MagPi site.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Source types.
+const (
+	// SourceTypeMagPi is a source publishing a bookshelf XML in the MagPi format.
+	SourceTypeMagPi = "magpi"
+	// SourceTypeDirectory is a local directory of PDFs served by the application.
+	SourceTypeDirectory = "directory"
+	// SourceTypeFeed is an RSS 2.0 or Atom feed publishing PDFs as enclosures.
+	SourceTypeFeed = "feed"
+)
+
+// magPiSourceName is the name of the built-in MagPi source.
+const magPiSourceName = "magpi"
+
+// SourceConfig holds the configuration of a catalog source aggregated with
+// the built-in MagPi source.
+type SourceConfig struct {
+	// Name identifies the source in the logs and in its health status.
+	Name string `yaml:"name"`
+	// Type is the kind of source, magpi, directory or feed.
+	Type string `yaml:"type"`
+	// URL is the location of the source catalog, for magpi and feed sources.
+	URL string `yaml:"url"`
+	// Timeout is the maximum duration of a request to the source.
+	// When zero, the MagPi timeout is used.
+	Timeout time.Duration `yaml:"timeout"`
+	// Path is the directory scanned by directory sources.
+	Path string `yaml:"path"`
+	// PollInterval is the time between two checks of a directory source for
+	// changes. When zero, the directory is checked every minute.
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// Category is the category of every book of a feed source.
+	// When empty, the categories of the feed are used.
+	Category string `yaml:"category"`
+	// Namespace is prepended to the categories of the source, so books of
+	// different sources are not mixed in the same category.
+	Namespace string `yaml:"namespace"`
+}
+
+// MirrorConfig holds the configuration of the local PDF mirror.
+type MirrorConfig struct {
+	// Enabled turns on the download of every available PDF.
+	Enabled bool `yaml:"enabled"`
+	// Dir is the directory where the PDFs are stored.
+	// When empty, a mirror directory inside the data directory is used.
+	Dir string `yaml:"dir"`
+	// Concurrency is the maximum number of simultaneous downloads.
+	Concurrency int `yaml:"concurrency"`
+	// Quota is the maximum disk space used by the PDFs, zero means unlimited.
+	Quota ByteSize `yaml:"quota"`
+	// Interval is the time between two synchronizations with the catalog.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// CoversConfig holds the configuration of the cover cache.
+type CoversConfig struct {
+	// Enabled turns on the local copy of the covers.
+	Enabled bool `yaml:"enabled"`
+	// Dir is the directory where the covers are stored.
+	// When empty, a covers directory inside the data directory is used.
+	Dir string `yaml:"dir"`
+	// Concurrency is the maximum number of simultaneous downloads.
+	Concurrency int `yaml:"concurrency"`
+	// Interval is the time between two synchronizations with the catalog.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// AdminConfig holds the configuration of the admin endpoints.
+type AdminConfig struct {
+	// Token is the bearer token required by the admin endpoints.
+	// When empty, the admin endpoints are disabled.
+	Token string `yaml:"token"`
+}
+
+// LogConfig holds the logging configuration.
+type LogConfig struct {
+	// Level is the minimum level of the logged messages.
+	// One of debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:         "0.0.0.0:8080",
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Storage: StorageConfig{
+			DataDir: "data",
+		},
+		Updater: UpdaterConfig{
+			Interval: 1 * time.Hour,
+			Retry: RetryConfig{
+				InitialInterval: 30 * time.Second,
+				MaxInterval:     15 * time.Minute,
+				Multiplier:      2,
+				Jitter:          0.2,
+			},
+			StaleAfter: 24 * time.Hour,
+		},
+		MagPi: MagPiConfig{
+			URL:     "https://magpi.raspberrypi.com/bookshelf.xml",
+			Timeout: 10 * time.Second,
+		},
+		Mirror: MirrorConfig{
+			Concurrency: 2,
+			Interval:    15 * time.Minute,
+		},
+		Covers: CoversConfig{
+			Enabled:     true,
+			Concurrency: 4,
+			Interval:    15 * time.Minute,
+		},
+		Log: LogConfig{
+			Level: "debug",
+		},
+	}
+}
+
+// setting describes a configuration value that can be set from a flag and
+// from an environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	// boolean settings can be given as a flag without value
+	boolean bool
+	apply   func(c *Config, value string) error
+}
+
+// settings lists every value that can be set from flags or environment
+// variables. Nested values, such as lists, are only available in the
+// configuration file.
+var settings = []setting{
+	{
+		flag:  "listen",
+		env:   "BOOKSHELF_LISTEN",
+		usage: "address the HTTP server listens on",
+		apply: func(c *Config, v string) error { c.Server.Address = v; return nil },
+	},
+	{
+		flag:  "shutdown-timeout",
+		env:   "BOOKSHELF_SHUTDOWN_TIMEOUT",
+		usage: "time given to in-flight requests to complete on shutdown",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Server.ShutdownTimeout, v) },
+	},
+	{
+		flag:  "data-dir",
+		env:   "BOOKSHELF_DATA_DIR",
+		usage: "directory where the catalog is persisted",
+		apply: func(c *Config, v string) error { c.Storage.DataDir = v; return nil },
+	},
+	{
+		flag:  "refresh-interval",
+		env:   "BOOKSHELF_REFRESH_INTERVAL",
+		usage: "time between catalog refreshes",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Updater.Interval, v) },
+	},
+	{
+		flag:  "refresh-schedule",
+		env:   "BOOKSHELF_REFRESH_SCHEDULE",
+		usage: "cron expressions, separated by semicolons, telling when the catalog is refreshed (overrides the interval)",
+		apply: func(c *Config, v string) error { c.Updater.Schedule = v; return nil },
+	},
+	{
+		flag:  "refresh-timezone",
+		env:   "BOOKSHELF_REFRESH_TIMEZONE",
+		usage: "timezone of the refresh schedule, such as Europe/London (default local)",
+		apply: func(c *Config, v string) error { c.Updater.Timezone = v; return nil },
+	},
+	{
+		flag:  "retry-initial-interval",
+		env:   "BOOKSHELF_RETRY_INITIAL_INTERVAL",
+		usage: "delay before retrying a failed refresh",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Updater.Retry.InitialInterval, v) },
+	},
+	{
+		flag:  "retry-max-interval",
+		env:   "BOOKSHELF_RETRY_MAX_INTERVAL",
+		usage: "maximum delay between two retries of a failed refresh",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Updater.Retry.MaxInterval, v) },
+	},
+	{
+		flag:  "retry-multiplier",
+		env:   "BOOKSHELF_RETRY_MULTIPLIER",
+		usage: "growth of the retry delay after each failed refresh",
+		apply: func(c *Config, v string) error { return parseFloat(&c.Updater.Retry.Multiplier, v) },
+	},
+	{
+		flag:  "retry-jitter",
+		env:   "BOOKSHELF_RETRY_JITTER",
+		usage: "fraction of the retry delay randomly added or removed, between 0 and 1",
+		apply: func(c *Config, v string) error { return parseFloat(&c.Updater.Retry.Jitter, v) },
+	},
+	{
+		flag:  "stale-after",
+		env:   "BOOKSHELF_STALE_AFTER",
+		usage: "age of the last successful refresh after which the readiness probe reports degraded, 0 to disable",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Updater.StaleAfter, v) },
+	},
+	{
+		flag:  "magpi-url",
+		env:   "BOOKSHELF_MAGPI_URL",
+		usage: "location of the MagPi bookshelf XML",
+		apply: func(c *Config, v string) error { c.MagPi.URL = v; return nil },
+	},
+	{
+		flag:  "magpi-timeout",
+		env:   "BOOKSHELF_MAGPI_TIMEOUT",
+		usage: "maximum duration of a request to the MagPi site",
+		apply: func(c *Config, v string) error { return parseDuration(&c.MagPi.Timeout, v) },
+	},
+	{
+		flag:    "mirror",
+		env:     "BOOKSHELF_MIRROR",
+		usage:   "download every available PDF and serve the local copy",
+		boolean: true,
+		apply:   func(c *Config, v string) error { return parseBool(&c.Mirror.Enabled, v) },
+	},
+	{
+		flag:  "mirror-dir",
+		env:   "BOOKSHELF_MIRROR_DIR",
+		usage: "directory where the mirrored PDFs are stored (default <data-dir>/mirror)",
+		apply: func(c *Config, v string) error { c.Mirror.Dir = v; return nil },
+	},
+	{
+		flag:  "mirror-concurrency",
+		env:   "BOOKSHELF_MIRROR_CONCURRENCY",
+		usage: "maximum number of simultaneous PDF downloads",
+		apply: func(c *Config, v string) error { return parseInt(&c.Mirror.Concurrency, v) },
+	},
+	{
+		flag:  "mirror-quota",
+		env:   "BOOKSHELF_MIRROR_QUOTA",
+		usage: "maximum disk space used by the mirrored PDFs, such as 10GB (0 is unlimited)",
+		apply: func(c *Config, v string) error { return c.Mirror.Quota.UnmarshalText([]byte(v)) },
+	},
+	{
+		flag:  "mirror-interval",
+		env:   "BOOKSHELF_MIRROR_INTERVAL",
+		usage: "time between two synchronizations of the mirror with the catalog",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Mirror.Interval, v) },
+	},
+	{
+		flag:    "covers",
+		env:     "BOOKSHELF_COVERS",
+		usage:   "keep a local copy of the covers and serve resized images",
+		boolean: true,
+		apply:   func(c *Config, v string) error { return parseBool(&c.Covers.Enabled, v) },
+	},
+	{
+		flag:  "covers-dir",
+		env:   "BOOKSHELF_COVERS_DIR",
+		usage: "directory where the covers are stored (default <data-dir>/covers)",
+		apply: func(c *Config, v string) error { c.Covers.Dir = v; return nil },
+	},
+	{
+		flag:  "covers-concurrency",
+		env:   "BOOKSHELF_COVERS_CONCURRENCY",
+		usage: "maximum number of simultaneous cover downloads",
+		apply: func(c *Config, v string) error { return parseInt(&c.Covers.Concurrency, v) },
+	},
+	{
+		flag:  "covers-interval",
+		env:   "BOOKSHELF_COVERS_INTERVAL",
+		usage: "time between two synchronizations of the covers with the catalog",
+		apply: func(c *Config, v string) error { return parseDuration(&c.Covers.Interval, v) },
+	},
+	{
+		flag:  "admin-token",
+		env:   "BOOKSHELF_ADMIN_TOKEN",
+		usage: "bearer token required by the admin endpoints, they are disabled when empty",
+		apply: func(c *Config, v string) error { c.Admin.Token = v; return nil },
+	},
+	{
+		flag:  "log-level",
+		env:   "BOOKSHELF_LOG_LEVEL",
+		usage: "minimum log level (debug, info, warn, error)",
+		apply: func(c *Config, v string) error { c.Log.Level = v; return nil },
+	},
+}
+
+// Load builds the configuration from the defaults, the optional
+// configuration file, the environment and the command-line arguments.
+// lookupEnv is usually os.LookupEnv.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	fs := flag.NewFlagSet("bookshelf", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	configFile := fs.String("config", "", "path to a YAML configuration file (env BOOKSHELF_CONFIG)")
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		set := func(v string) error {
+			flagValues[s.flag] = v
+			return nil
+		}
+		if s.boolean {
+			fs.BoolFunc(s.flag, usage, set)
+		} else {
+			fs.Func(s.flag, usage, set)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return Config{}, err
+	}
+
+	cfg := Default()
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("BOOKSHELF_CONFIG")
+	}
+	if path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if v, ok := lookupEnv(s.env); ok {
+			if err := s.apply(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flagValues[s.flag]; ok {
+			if err := s.apply(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", s.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// loadFile overrides cfg with the values present in the YAML file at path.
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open config file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			slog.Error("Cannot close config file", slog.Any("error", err))
+		}
+	}()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot decode config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the configuration is usable.
+// All problems are reported at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		errs = append(errs, fmt.Errorf("server.address: %w", err))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		// a zero timeout would abort every in-flight request on shutdown
+		errs = append(errs, errors.New("server.shutdownTimeout: must be positive"))
+	}
+	if c.Storage.DataDir == "" {
+		errs = append(errs, errors.New("storage.dataDir: is required"))
+	}
+	if c.Updater.Interval <= 0 {
+		errs = append(errs, errors.New("updater.interval: must be positive"))
+	}
+	if _, err := c.Updater.RefreshSchedule(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Updater.Retry.InitialInterval <= 0 {
+		errs = append(errs, errors.New("updater.retry.initialInterval: must be positive"))
+	}
+	if c.Updater.Retry.MaxInterval < c.Updater.Retry.InitialInterval {
+		errs = append(errs, errors.New("updater.retry.maxInterval: must not be lower than the initial interval"))
+	}
+	if c.Updater.Retry.Multiplier < 1 {
+		errs = append(errs, errors.New("updater.retry.multiplier: must be at least 1"))
+	}
+	if c.Updater.Retry.Jitter < 0 || c.Updater.Retry.Jitter > 1 {
+		errs = append(errs, errors.New("updater.retry.jitter: must be between 0 and 1"))
+	}
+	if c.Updater.StaleAfter < 0 {
+		errs = append(errs, errors.New("updater.staleAfter: must not be negative"))
+	}
+	if err := validateHTTPURL(c.MagPi.URL); err != nil {
+		errs = append(errs, fmt.Errorf("magpi.url: %w", err))
+	}
+	if c.MagPi.Timeout <= 0 {
+		errs = append(errs, errors.New("magpi.timeout: must be positive"))
+	}
+	names := map[string]bool{magPiSourceName: true}
+	for i, source := range c.Sources {
+		field := fmt.Sprintf("sources[%d]", i)
+		switch {
+		case source.Name == "":
+			errs = append(errs, fmt.Errorf("%s.name: is required", field))
+		case names[source.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: %q is already used", field, source.Name))
+		}
+		names[source.Name] = true
+		switch source.Type {
+		case SourceTypeMagPi, SourceTypeFeed:
+			if err := validateHTTPURL(source.URL); err != nil {
+				errs = append(errs, fmt.Errorf("%s.url: %w", field, err))
+			}
+		case SourceTypeDirectory:
+			if source.Path == "" {
+				errs = append(errs, fmt.Errorf("%s.path: is required", field))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s.type: unknown type %q", field, source.Type))
+		}
+		if source.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("%s.timeout: must not be negative", field))
+		}
+		if source.PollInterval < 0 {
+			errs = append(errs, fmt.Errorf("%s.pollInterval: must not be negative", field))
+		}
+	}
+	if c.Mirror.Enabled {
+		if c.Mirror.Concurrency < 1 {
+			errs = append(errs, errors.New("mirror.concurrency: must be at least 1"))
+		}
+		if c.Mirror.Interval <= 0 {
+			errs = append(errs, errors.New("mirror.interval: must be positive"))
+		}
+	}
+	if c.Mirror.Quota < 0 {
+		errs = append(errs, errors.New("mirror.quota: must not be negative"))
+	}
+	if c.Covers.Enabled {
+		if c.Covers.Concurrency < 1 {
+			errs = append(errs, errors.New("covers.concurrency: must be at least 1"))
+		}
+		if c.Covers.Interval <= 0 {
+			errs = append(errs, errors.New("covers.interval: must be positive"))
+		}
+	}
+	if _, err := c.Log.SlogLevel(); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// RefreshSchedule returns the schedule of the catalog refreshes, the cron
+// expressions when they are set, otherwise the interval.
+func (c UpdaterConfig) RefreshSchedule() (schedule.Schedule, error) {
+	loc := time.Local
+	if c.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(c.Timezone); err != nil {
+			return nil, fmt.Errorf("updater.timezone: %w", err)
+		}
+	}
+	if strings.TrimSpace(c.Schedule) == "" {
+		return schedule.Every(c.Interval), nil
+	}
+	s, err := schedule.Parse(c.Schedule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("updater.schedule: %w", err)
+	}
+	return s, nil
+}
+
+// SlogLevel returns the slog.Level matching the configured level name.
+func (c LogConfig) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(c.Level))); err != nil {
+		return 0, fmt.Errorf("unknown level %q", c.Level)
+	}
+	return level, nil
+}
+
+func parseDuration(dst *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*dst = d
+	return nil
+}
+
+func parseBool(dst *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+func parseFloat(dst *float64, value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*dst = f
+	return nil
+}
+
+func parseInt(dst *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*dst = n
+	return nil
+}
+
+func validateHTTPURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("host is required")
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  address: "127.0.0.1:9000"
+updater:
+  interval: 30m
+  retry:
+    initialInterval: 10s
+    jitter: 0.5
+magpi:
+  timeout: 5s
+log:
+  level: warn
+`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-refresh-interval", "2h"},
+		env(map[string]string{
+			"BOOKSHELF_REFRESH_INTERVAL": "45m",
+			"BOOKSHELF_MAGPI_TIMEOUT":    "20s",
+			"BOOKSHELF_RETRY_MULTIPLIER": "3",
+			"BOOKSHELF_STALE_AFTER":      "6h",
+		}),
+	)
+	require.NoError(t, err)
+
+	// file overrides defaults
+	assert.Equal(t, "127.0.0.1:9000", cfg.Server.Address)
+	assert.Equal(t, "warn", cfg.Log.Level)
+	assert.Equal(t, 10*time.Second, cfg.Updater.Retry.InitialInterval)
+	assert.Equal(t, 0.5, cfg.Updater.Retry.Jitter)
+	assert.Equal(t, 3.0, cfg.Updater.Retry.Multiplier)
+	// environment overrides file
+	assert.Equal(t, 20*time.Second, cfg.MagPi.Timeout)
+	assert.Equal(t, 6*time.Hour, cfg.Updater.StaleAfter)
+	// flags override environment
+	assert.Equal(t, 2*time.Hour, cfg.Updater.Interval)
+	// untouched values keep their defaults
+	assert.Equal(t, Default().MagPi.URL, cfg.MagPi.URL)
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	path := writeConfigFile(t, "storage:\n  dataDir: /var/lib/bookshelf\n")
+
+	cfg, err := Load(nil, env(map[string]string{"BOOKSHELF_CONFIG": path}))
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/bookshelf", cfg.Storage.DataDir)
+}
+
+func TestLoadRejectsUnknownFileFields(t *testing.T) {
+	path := writeConfigFile(t, "server:\n  adress: \":8080\"\n")
+
+	_, err := Load([]string{"-config", path}, env(nil))
+	assert.ErrorContains(t, err, "field adress not found")
+}
+
+func TestLoadSources(t *testing.T) {
+	path := writeConfigFile(t, `
+sources:
+  - name: hackspace
+    type: magpi
+    url: https://hackspace.example.com/bookshelf.xml
+    namespace: HackSpace
+  - name: wireframe
+    type: magpi
+    url: https://wireframe.example.com/bookshelf.xml
+    timeout: 30s
+  - name: nas
+    type: directory
+    path: /srv/nas/magazines
+    pollInterval: 5m
+  - name: makers
+    type: feed
+    url: https://makers.example.com/feed.rss
+    category: Makers
+`)
+
+	cfg, err := Load([]string{"-config", path}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []SourceConfig{
+		{
+			Name:      "hackspace",
+			Type:      SourceTypeMagPi,
+			URL:       "https://hackspace.example.com/bookshelf.xml",
+			Namespace: "HackSpace",
+		},
+		{
+			Name:    "wireframe",
+			Type:    SourceTypeMagPi,
+			URL:     "https://wireframe.example.com/bookshelf.xml",
+			Timeout: 30 * time.Second,
+		},
+		{
+			Name:         "nas",
+			Type:         SourceTypeDirectory,
+			Path:         "/srv/nas/magazines",
+			PollInterval: 5 * time.Minute,
+		},
+		{
+			Name:     "makers",
+			Type:     SourceTypeFeed,
+			URL:      "https://makers.example.com/feed.rss",
+			Category: "Makers",
+		},
+	}, cfg.Sources)
+}
+
+func TestLoadRejectsDuplicateSourceNames(t *testing.T) {
+	path := writeConfigFile(t, `
+sources:
+  - name: nas
+    type: magpi
+    url: http://nas.local/a.xml
+  - name: nas
+    type: magpi
+    url: http://nas.local/b.xml
+`)
+
+	_, err := Load([]string{"-config", path}, env(nil))
+	assert.ErrorContains(t, err, "sources[1].name")
+}
+
+func TestRefreshSchedule(t *testing.T) {
+	cfg := Default()
+	s, err := cfg.Updater.RefreshSchedule()
+	require.NoError(t, err)
+	assert.Equal(t, schedule.Every(time.Hour), s, "the interval is used without a schedule")
+
+	cfg, err = Load([]string{
+		"-refresh-schedule", "*/15 * * * 4L; 0 3 * * *",
+		"-refresh-timezone", "Europe/London",
+	}, env(nil))
+	require.NoError(t, err)
+	s, err = cfg.Updater.RefreshSchedule()
+	require.NoError(t, err)
+	next := s.Next(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC), next.UTC(), "03:00 in London in summer")
+
+	_, err = Load([]string{"-refresh-timezone", "Mars/Olympus"}, env(nil))
+	assert.ErrorContains(t, err, "updater.timezone")
+}
+
+func TestLoadInvalidDuration(t *testing.T) {
+	_, err := Load(nil, env(map[string]string{"BOOKSHELF_REFRESH_INTERVAL": "soon"}))
+	assert.ErrorContains(t, err, "env BOOKSHELF_REFRESH_INTERVAL")
+}
+
+func TestLoadBooleanFlag(t *testing.T) {
+	cfg, err := Load([]string{"-mirror", "-mirror-quota", "1.5GiB"}, env(nil))
+	require.NoError(t, err)
+	assert.True(t, cfg.Mirror.Enabled)
+	assert.Equal(t, ByteSize(1.5*(1<<30)), cfg.Mirror.Quota)
+
+	cfg, err = Load([]string{"-mirror=false"}, env(map[string]string{"BOOKSHELF_MIRROR": "true"}))
+	require.NoError(t, err)
+	assert.False(t, cfg.Mirror.Enabled)
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]ByteSize{
+		"0":      0,
+		"1024":   1024,
+		"10B":    10,
+		"500MB":  500_000_000,
+		"2 GiB":  2 << 30,
+		"1.5kib": 1536,
+	}
+	for value, expected := range tests {
+		size, err := ParseByteSize(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	_, err := ParseByteSize("lots")
+	assert.Error(t, err)
+	_, err = ParseByteSize("-1GB")
+	assert.Error(t, err)
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Config{
+		Server: ServerConfig{Address: "localhost"},
+		Updater: UpdaterConfig{
+			Interval:   -time.Second,
+			Schedule:   "0 3 * * * *",
+			Retry:      RetryConfig{MaxInterval: -time.Second, Multiplier: 0.5, Jitter: 2},
+			StaleAfter: -time.Second,
+		},
+		MagPi: MagPiConfig{URL: "ftp://example.com/bookshelf.xml"},
+		Sources: []SourceConfig{
+			{Name: "magpi", Type: "rss", URL: "http://example.com/feed.xml", Timeout: -time.Second},
+			{Name: "nas", Type: SourceTypeDirectory, PollInterval: -time.Second},
+			{Name: "makers", Type: SourceTypeFeed},
+		},
+		Mirror: MirrorConfig{Enabled: true, Quota: -1},
+		Covers: CoversConfig{Enabled: true},
+		Log:    LogConfig{Level: "verbose"},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, field := range []string{
+		"server.address",
+		"server.shutdownTimeout",
+		"storage.dataDir",
+		"updater.interval",
+		"updater.schedule",
+		"updater.retry.initialInterval",
+		"updater.retry.maxInterval",
+		"updater.retry.multiplier",
+		"updater.retry.jitter",
+		"updater.staleAfter",
+		"magpi.url",
+		"magpi.timeout",
+		"sources[0].name",
+		"sources[0].type",
+		"sources[0].timeout",
+		"sources[1].path",
+		"sources[1].pollInterval",
+		"sources[2].url",
+		"mirror.concurrency",
+		"mirror.interval",
+		"mirror.quota",
+		"covers.concurrency",
+		"covers.interval",
+		"log.level",
+	} {
+		assert.ErrorContains(t, err, field)
+	}
+}
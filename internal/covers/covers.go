@@ -0,0 +1,369 @@
+// Package covers keeps a local copy of the book covers, in the sizes used
+// by the pages, so they are fetched from the upstream site only once.
+package covers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// decoders for the formats covers are published in
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/atomicfile"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"golang.org/x/sync/errgroup"
+)
+
+// Size is the size of a generated cover image.
+type Size string
+
+const (
+	// SizeThumbnail is used in the books grid.
+	SizeThumbnail Size = "thumb"
+	// SizeLarge is used in the book details.
+	SizeLarge Size = "large"
+)
+
+// widths are the widths of the generated images, twice the displayed size
+// so they stay sharp on high density screens.
+var widths = map[Size]int{
+	SizeThumbnail: 300,
+	SizeLarge:     600,
+}
+
+const (
+	indexFileName = "index.json"
+	// indexFileVersion is the current version of the index file format.
+	indexFileVersion = 1
+	// maxCoverSize is the largest upstream cover that is accepted.
+	maxCoverSize = 20 << 20
+	// maxCoverPixels is the largest cover that is decoded, a small file can
+	// hold an image too large to decode in memory.
+	maxCoverPixels = 6000 * 6000
+	jpegQuality    = 85
+)
+
+// ParseSize returns the Size with the given name.
+func ParseSize(name string) (Size, bool) {
+	size := Size(name)
+	_, ok := widths[size]
+	return size, ok
+}
+
+// URL returns the local URL of the cover of the book in the given size.
+// It includes a version derived from the upstream cover URL, so the browser
+// fetches the image again when the upstream cover changes.
+func URL(book entities.Book, size Size) string {
+	return fmt.Sprintf("/covers/%s/%s?v=%s", url.PathEscape(book.ID), size, Version(book))
+}
+
+// Version returns the version of the cover of the book in its URL.
+func Version(book entities.Book) string {
+	sum := sha256.Sum256([]byte(book.Cover))
+	return hex.EncodeToString(sum[:4])
+}
+
+// BookSource provides the books whose covers should be cached.
+type BookSource interface {
+	Get(ctx context.Context, q entities.BookQuery) (entities.BookPage, error)
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Dir is the directory where the images are stored.
+	Dir string
+	// Concurrency is the maximum number of simultaneous downloads.
+	Concurrency int
+	// Interval is the time between two synchronizations with the catalog.
+	Interval time.Duration
+	// HTTPClient is used for the downloads, http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// entry describes the cached cover of a book.
+type entry struct {
+	// Source is the upstream URL the cover was fetched from.
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+type indexFile struct {
+	Version int              `json:"version"`
+	Entries map[string]entry `json:"entries"`
+}
+
+// Manager fetches the cover of every book, generates the resized images
+// and keeps track of what was cached.
+type Manager struct {
+	books   BookSource
+	options Options
+
+	mu      sync.RWMutex
+	entries map[string]entry
+}
+
+// NewManager creates a new instance of Manager.
+// It reads the index of the covers cached by a previous run.
+func NewManager(books BookSource, options Options) (*Manager, error) {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create covers directory: %w", err)
+	}
+
+	m := &Manager{
+		books:   books,
+		options: options,
+		entries: map[string]entry{},
+	}
+	if err := m.loadIndex(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run periodically synchronizes the cache with the catalog until the
+// provided context is done.
+// This operation is blocking, you might want to run it in a separate goroutine.
+func (m *Manager) Run(ctx context.Context) error {
+	slog.Debug("starting the cover cache", slog.String("dir", m.options.Dir))
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("context is done, exiting the cover cache")
+			return nil
+		case <-timer.C:
+			if err := m.Sync(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to synchronize the cover cache", slog.Any("error", err))
+			}
+			timer.Reset(m.options.Interval)
+		}
+	}
+}
+
+// Lookup returns the path of the cached cover of the book in the given
+// size. Covers cached from a different upstream URL are not returned.
+func (m *Manager) Lookup(_ context.Context, book entities.Book, size Size) (string, bool) {
+	m.mu.RLock()
+	e, ok := m.entries[book.ID]
+	m.mu.RUnlock()
+	if !ok || e.Source != book.Cover {
+		return "", false
+	}
+	return m.imagePath(book.ID, size), true
+}
+
+// Sync fetches the covers that are not cached yet, or whose upstream URL
+// changed, and removes the covers of the books that left the catalog.
+func (m *Manager) Sync(ctx context.Context) error {
+	catalog, err := m.books.Get(ctx, entities.BookQuery{})
+	if err != nil {
+		return fmt.Errorf("cannot get books: %w", err)
+	}
+	books := catalog.Books
+
+	if err := m.prune(books); err != nil {
+		slog.ErrorContext(ctx, "failed to prune the cover cache", slog.Any("error", err))
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(m.options.Concurrency)
+	for _, b := range books {
+		// covers of local sources are already served by the application
+		if !entities.IsRemote(b.Cover) || m.isCached(b) {
+			continue
+		}
+		g.Go(func() error {
+			if err := m.fetch(ctx, b); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to cache cover",
+					slog.String("bookID", b.ID),
+					slog.String("cover", b.Cover),
+					slog.Any("error", err),
+				)
+			}
+			// one failed download must not cancel the others
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func (m *Manager) isCached(b entities.Book) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.entries[b.ID]
+	return ok && e.Source == b.Cover
+}
+
+// prune removes the covers of the books that are not in the catalog anymore.
+func (m *Manager) prune(books []entities.Book) error {
+	ids := make(map[string]bool, len(books))
+	for _, b := range books {
+		ids[b.ID] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	removed := false
+	for id := range m.entries {
+		if !ids[id] {
+			delete(m.entries, id)
+			errs = append(errs, os.RemoveAll(m.bookDir(id)))
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	errs = append(errs, m.saveIndex())
+	return errors.Join(errs...)
+}
+
+// fetch downloads the cover of the book and generates every size.
+func (m *Manager) fetch(ctx context.Context, b entities.Book) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.Cover, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.options.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("Cannot close response body", slog.Any("error", err))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverSize))
+	if err != nil {
+		return err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot decode cover: %w", err)
+	}
+	if config.Width*config.Height > maxCoverPixels {
+		return fmt.Errorf("cover of %dx%d pixels is too large", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot decode cover: %w", err)
+	}
+
+	if err := os.MkdirAll(m.bookDir(b.ID), 0o755); err != nil {
+		return err
+	}
+	for size, width := range widths {
+		if err := writeJPEG(m.imagePath(b.ID, size), resize(img, width)); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[b.ID] = entry{
+		Source:    b.Cover,
+		FetchedAt: time.Now().UTC(),
+	}
+	slog.DebugContext(ctx, "cached cover", slog.String("bookID", b.ID))
+	return m.saveIndex()
+}
+
+func (m *Manager) bookDir(bookID string) string {
+	// the ID is hashed so it is always a safe file name
+	sum := sha256.Sum256([]byte(bookID))
+	return filepath.Join(m.options.Dir, hex.EncodeToString(sum[:16]))
+}
+
+func (m *Manager) imagePath(bookID string, size Size) string {
+	return filepath.Join(m.bookDir(bookID), string(size)+".jpg")
+}
+
+// writeJPEG atomically writes img to path. Transparent areas become white.
+func writeJPEG(path string, img *image.RGBA) error {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	return atomicfile.Write(path, func(w io.Writer) error {
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+	})
+}
+
+func (m *Manager) indexPath() string {
+	return filepath.Join(m.options.Dir, indexFileName)
+}
+
+// loadIndex reads the index file, a missing file is an empty index.
+func (m *Manager) loadIndex() error {
+	data, err := os.ReadFile(m.indexPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cannot decode covers index: %w", err)
+	}
+	if file.Version < 1 || file.Version > indexFileVersion {
+		return fmt.Errorf("unsupported covers index version %d", file.Version)
+	}
+	if file.Entries != nil {
+		m.entries = file.Entries
+	}
+	return nil
+}
+
+// saveIndex atomically writes the index file.
+// It must be called with the lock held.
+func (m *Manager) saveIndex() error {
+	data, err := json.MarshalIndent(indexFile{
+		Version: indexFileVersion,
+		Entries: m.entries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(m.indexPath(), data)
+}
@@ -0,0 +1,191 @@
+package covers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticBooks []entities.Book
+
+func (s staticBooks) Get(_ context.Context, _ entities.BookQuery) (entities.BookPage, error) {
+	return entities.BookPage{Books: s, Total: len(s)}, nil
+}
+
+func encodePNG(t *testing.T, width, height int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// withPNGSize returns the PNG with the size in its header replaced, the
+// pixels are left untouched.
+func withPNGSize(t *testing.T, data []byte, width, height int) []byte {
+	t.Helper()
+	data = bytes.Clone(data)
+	// the IHDR chunk follows the 8 bytes signature, its data starts after
+	// its length and type
+	ihdr := data[8+4 : 8+4+4+13]
+	require.Equal(t, "IHDR", string(ihdr[:4]))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[8:], uint32(height))
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func decodeJPEG(t *testing.T, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	img, err := jpeg.Decode(f)
+	require.NoError(t, err)
+	return img
+}
+
+func newCoverServer(t *testing.T, covers map[string][]byte) (*httptest.Server, *atomic.Int64) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		content, ok := covers[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	// left half black, right half white
+	for y := range 2 {
+		for x := range 4 {
+			if x >= 2 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	dst := resize(src, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, dst.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, dst.RGBAAt(1, 0))
+
+	// small images are not scaled up
+	assert.Equal(t, src.Bounds(), resize(src, 100).Bounds())
+}
+
+func TestSyncGeneratesEverySize(t *testing.T) {
+	server, requests := newCoverServer(t, map[string][]byte{
+		"/1.png": encodePNG(t, 900, 1350, color.RGBA{200, 0, 0, 255}),
+	})
+	book := entities.Book{ID: "1", Cover: server.URL + "/1.png"}
+
+	dir := t.TempDir()
+	m, err := NewManager(staticBooks{book}, Options{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, m.Sync(t.Context()))
+
+	thumb, ok := m.Lookup(t.Context(), book, SizeThumbnail)
+	require.True(t, ok)
+	assert.Equal(t, image.Rect(0, 0, 300, 450), decodeJPEG(t, thumb).Bounds())
+
+	large, ok := m.Lookup(t.Context(), book, SizeLarge)
+	require.True(t, ok)
+	assert.Equal(t, image.Rect(0, 0, 600, 900), decodeJPEG(t, large).Bounds())
+
+	// cached covers are not fetched again, even after a restart
+	restarted, err := NewManager(staticBooks{book}, Options{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, restarted.Sync(t.Context()))
+	assert.Equal(t, int64(1), requests.Load())
+}
+
+func TestSyncRefreshesChangedCover(t *testing.T) {
+	server, _ := newCoverServer(t, map[string][]byte{
+		"/old.png": encodePNG(t, 10, 10, color.Black),
+		"/new.png": encodePNG(t, 10, 10, color.White),
+	})
+	book := entities.Book{ID: "1", Cover: server.URL + "/old.png"}
+
+	m, err := NewManager(staticBooks{book}, Options{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, m.Sync(t.Context()))
+
+	book.Cover = server.URL + "/new.png"
+	_, ok := m.Lookup(t.Context(), book, SizeThumbnail)
+	assert.False(t, ok, "a cover cached from another URL is stale")
+
+	m.books = staticBooks{book}
+	require.NoError(t, m.Sync(t.Context()))
+	path, ok := m.Lookup(t.Context(), book, SizeThumbnail)
+	require.True(t, ok)
+	r, g, b, _ := decodeJPEG(t, path).At(5, 5).RGBA()
+	assert.Greater(t, r+g+b, uint32(3*0xf000), "the new white cover should be cached")
+}
+
+func TestSyncSkipsUndecodableCovers(t *testing.T) {
+	server, _ := newCoverServer(t, map[string][]byte{
+		"/broken.png": []byte("not an image"),
+	})
+	book := entities.Book{ID: "1", Cover: server.URL + "/broken.png"}
+	missing := entities.Book{ID: "2", Cover: server.URL + "/missing.png"}
+
+	m, err := NewManager(staticBooks{book, missing}, Options{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, m.Sync(t.Context()))
+
+	_, ok := m.Lookup(t.Context(), book, SizeThumbnail)
+	assert.False(t, ok)
+	_, ok = m.Lookup(t.Context(), missing, SizeThumbnail)
+	assert.False(t, ok)
+}
+
+func TestFetchRejectsHugeCovers(t *testing.T) {
+	server, _ := newCoverServer(t, map[string][]byte{
+		"/huge.png": withPNGSize(t, encodePNG(t, 10, 10, color.Black), 7000, 7000),
+	})
+	book := entities.Book{ID: "1", Cover: server.URL + "/huge.png"}
+
+	m, err := NewManager(staticBooks{book}, Options{Dir: t.TempDir()})
+	require.NoError(t, err)
+	assert.ErrorContains(t, m.fetch(t.Context(), book), "too large", "the cover is rejected before it is decoded")
+
+	_, ok := m.Lookup(t.Context(), book, SizeThumbnail)
+	assert.False(t, ok)
+}
+
+func TestURLChangesWithCover(t *testing.T) {
+	book := entities.Book{ID: "abc", Cover: "http://localhost/covers/1"}
+	first := URL(book, SizeThumbnail)
+	assert.Regexp(t, `^/covers/abc/thumb\?v=[0-9a-f]{8}$`, first)
+
+	book.Cover = "http://cdn.localhost/covers/1"
+	assert.NotEqual(t, first, URL(book, SizeThumbnail))
+}
@@ -1,5 +1,31 @@
 package entities
 
+import (
+	"net/url"
+	"path"
+	"time"
+)
+
+// Availability tells whether the PDF of a book can be downloaded.
+type Availability string
+
+const (
+	// AvailabilityAvailable is a book whose PDF can be downloaded.
+	AvailabilityAvailable Availability = "available"
+	// AvailabilityLocked is a book that is announced but not published yet.
+	AvailabilityLocked Availability = "locked"
+)
+
+// ParseAvailability returns the Availability with the given name.
+func ParseAvailability(name string) (Availability, bool) {
+	switch a := Availability(name); a {
+	case AvailabilityAvailable, AvailabilityLocked:
+		return a, true
+	default:
+		return "", false
+	}
+}
+
 // Book represents a book or magazine entity.
 // It is part of the domain layer and used across the application.
 type Book struct {
@@ -9,4 +35,47 @@ type Book struct {
 	Cover       string
 	Link        string
 	Category    string
+	// File is the name of the PDF file upstream, it is known even before
+	// the book is published.
+	File         string
+	Availability Availability
+	// Source is the name of the catalog source the book comes from.
+	Source string
+	// Issue is the number of a magazine issue, 0 when unknown.
+	Issue int
+	// Year and Month are when the book was published, 0 when unknown.
+	// The month is only known with the year.
+	Year  int
+	Month time.Month
+	// Edition is the edition of a book, such as 5 for its 5th edition,
+	// 0 when unknown.
+	Edition int
+	// Added is when the book was first seen in the catalog, it is set by
+	// the storage.
+	Added time.Time
+}
+
+// IsLocked checks if the book is announced but cannot be downloaded yet.
+func (b Book) IsLocked() bool {
+	return b.Availability == AvailabilityLocked
+}
+
+// IsRemote reports whether link is an absolute HTTP URL, rather than a file
+// served by the application itself.
+func IsRemote(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// FileName returns the name of the file pointed by link, or an empty string
+// when its path does not name a file.
+func FileName(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if name := path.Base(u.Path); name != "/" && name != "." {
+		return name
+	}
+	return ""
 }
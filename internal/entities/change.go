@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// ChangeKind describes how a book changed between two catalogs.
+type ChangeKind string
+
+const (
+	// ChangeAdded is a book that was not in the previous catalog.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is a book that is not in the catalog anymore.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeUnlocked is a book whose PDF became available.
+	ChangeUnlocked ChangeKind = "unlocked"
+)
+
+// Change represents a change detected in the catalog.
+type Change struct {
+	Kind ChangeKind
+	// Book is the book after the change, or before it when it was removed.
+	Book Book
+	// At is the time the change was detected.
+	At time.Time
+}
@@ -0,0 +1,89 @@
+package entities
+
+import "errors"
+
+// ErrUnknownCursor is returned when the cursor of a BookQuery is not a book
+// of the listed books, for instance because it was removed from the catalog.
+var ErrUnknownCursor = errors.New("unknown cursor")
+
+// SortField is what the books are sorted by.
+type SortField string
+
+const (
+	// SortDefault keeps the order of the sources, or the best match first
+	// when searching.
+	SortDefault SortField = ""
+	SortTitle   SortField = "title"
+	// SortIssue sorts by issue number, the books without one come last.
+	SortIssue SortField = "issue"
+	// SortAdded sorts by the time the books were first seen in the catalog.
+	SortAdded SortField = "added"
+)
+
+// ParseSortField returns the SortField with the given name.
+func ParseSortField(name string) (SortField, bool) {
+	switch f := SortField(name); f {
+	case SortDefault, SortTitle, SortIssue, SortAdded:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// DefaultOrder returns the order used when the query does not give one:
+// ascending for the titles, and descending for the issue numbers and the
+// time added, so the newest books come first.
+func (f SortField) DefaultOrder() SortOrder {
+	switch f {
+	case SortIssue, SortAdded:
+		return OrderDesc
+	default:
+		return OrderAsc
+	}
+}
+
+// SortOrder is the direction of the sort.
+type SortOrder string
+
+const (
+	// OrderDefault is the SortField.DefaultOrder of the field.
+	OrderDefault SortOrder = ""
+	OrderAsc     SortOrder = "asc"
+	OrderDesc    SortOrder = "desc"
+)
+
+// ParseSortOrder returns the SortOrder with the given name.
+func ParseSortOrder(name string) (SortOrder, bool) {
+	switch o := SortOrder(name); o {
+	case OrderDefault, OrderAsc, OrderDesc:
+		return o, true
+	default:
+		return "", false
+	}
+}
+
+// BookQuery selects, sorts and pages the books of the catalog.
+// The zero value lists every book in the order of the sources.
+type BookQuery struct {
+	// Category only keeps the books of the category, when not empty.
+	Category string
+	// Availability only keeps the books with the availability, when not
+	// empty.
+	Availability Availability
+	Sort         SortField
+	Order        SortOrder
+	// Limit is the maximum number of books of a page, 0 for no limit.
+	Limit int
+	// Cursor is the Next cursor of the previous page, empty for the first
+	// page.
+	Cursor string
+}
+
+// BookPage is a page of the books selected by a BookQuery.
+type BookPage struct {
+	Books []Book
+	// Total is the number of books selected by the query, on every page.
+	Total int
+	// Next is the cursor of the next page, empty on the last page.
+	Next string
+}
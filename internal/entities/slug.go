@@ -0,0 +1,25 @@
+package entities
+
+import "strings"
+
+// Slug turns a text into a readable URL segment, made of the lower case
+// ASCII letters and digits of the text separated by dashes.
+func Slug(text string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case 'a' <= r && r <= 'z' || '0' <= r && r <= '9':
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(r)
+		case r == '\'' || r == '’':
+			// keep the words with an apostrophe together
+		default:
+			dash = true
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUnchanged is returned by a catalog source when its books did not change
+// since its previous successful fetch.
+var ErrUnchanged = errors.New("books are unchanged")
+
+// SourceHealth describes the state of a catalog source after its last refresh.
+type SourceHealth struct {
+	Name string
+	// Healthy is true when the last refresh of the source succeeded.
+	Healthy bool
+	// Books is the number of books currently served from the source.
+	Books       int
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+}
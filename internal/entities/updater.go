@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// UpdaterStatus describes the refreshes of the catalog.
+type UpdaterStatus struct {
+	// Running is true while a refresh is in progress.
+	Running     bool
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+	// Failures is the number of consecutive failed refreshes.
+	Failures int
+	// NextRun is when the next refresh is scheduled, unless one is
+	// requested before.
+	NextRun time.Time
+	// Books, Locked and Categories count the current catalog.
+	Books      int
+	Locked     int
+	Categories int
+}
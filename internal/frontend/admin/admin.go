@@ -0,0 +1,139 @@
+// Package admin implements the operator endpoints of the bookshelf, such as
+// refreshing the catalog without restarting the application.
+// Every endpoint requires the configured token.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/httpjson"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// RefreshFn asks the updater to refresh the catalog now, it does not wait
+	// for the refresh to complete.
+	RefreshFn = func()
+	// GetStatusFn returns the status of the catalog refreshes.
+	GetStatusFn = func(ctx context.Context) (entities.UpdaterStatus, error)
+
+	// Status is the JSON representation of the status of the catalog refreshes.
+	Status struct {
+		Running     bool       `json:"running"`
+		LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+		LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+		LastError   string     `json:"lastError,omitempty"`
+		LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+		Failures    int        `json:"failures"`
+		NextRun     *time.Time `json:"nextRun,omitempty"`
+		Books       int        `json:"books"`
+		Locked      int        `json:"locked"`
+		Categories  int        `json:"categories"`
+	}
+)
+
+// NewRouter creates the router of the admin endpoints, every request must
+// carry the token as a bearer token.
+// It is meant to be mounted under /admin.
+func NewRouter(token string, refreshFn RefreshFn, getStatusFn GetStatusFn) chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireToken(token))
+
+	r.Post("/refresh", NewRefreshHandler(refreshFn, getStatusFn).ServeHTTP)
+	r.Get("/status", NewStatusHandler(getStatusFn).ServeHTTP)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		httpjson.WriteError(w, http.StatusNotFound, "not_found", "resource not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		httpjson.WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	})
+
+	return r
+}
+
+// NewStatus converts an entities.UpdaterStatus to its JSON representation.
+func NewStatus(s entities.UpdaterStatus) Status {
+	return Status{
+		Running:     s.Running,
+		LastAttempt: httpjson.OptionalTime(s.LastAttempt),
+		LastSuccess: httpjson.OptionalTime(s.LastSuccess),
+		LastError:   s.LastError,
+		LastErrorAt: httpjson.OptionalTime(s.LastErrorAt),
+		Failures:    s.Failures,
+		NextRun:     httpjson.OptionalTime(s.NextRun),
+		Books:       s.Books,
+		Locked:      s.Locked,
+		Categories:  s.Categories,
+	}
+}
+
+// RefreshHandler requests a refresh of the catalog.
+type RefreshHandler struct {
+	refreshFn   RefreshFn
+	getStatusFn GetStatusFn
+}
+
+// NewRefreshHandler creates a new RefreshHandler with the provided RefreshFn and GetStatusFn.
+// This handler wakes the updater up and answers right away with the current
+// status, requests made while a refresh is pending are merged into it.
+func NewRefreshHandler(refresh RefreshFn, getStatus GetStatusFn) *RefreshHandler {
+	return &RefreshHandler{
+		refreshFn:   refresh,
+		getStatusFn: getStatus,
+	}
+}
+
+func (h *RefreshHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.refreshFn()
+	slog.InfoContext(r.Context(), "catalog refresh requested", slog.String("remoteAddr", r.RemoteAddr))
+
+	status, err := h.getStatusFn(r.Context())
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "internal", "error fetching status")
+		return
+	}
+	httpjson.Write(w, http.StatusAccepted, NewStatus(status))
+}
+
+// StatusHandler serves the status of the catalog refreshes.
+type StatusHandler struct {
+	getStatusFn GetStatusFn
+}
+
+// NewStatusHandler creates a new StatusHandler with the provided GetStatusFn.
+func NewStatusHandler(getStatus GetStatusFn) *StatusHandler {
+	return &StatusHandler{
+		getStatusFn: getStatus,
+	}
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, err := h.getStatusFn(r.Context())
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "internal", "error fetching status")
+		return
+	}
+	httpjson.Write(w, http.StatusOK, NewStatus(status))
+}
+
+// requireToken rejects the requests without the bearer token.
+func requireToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				httpjson.WriteError(w, http.StatusUnauthorized, "unauthorized", "a valid token is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "s3cret"
+
+var lastSuccess = time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func getStatus(context.Context) (entities.UpdaterStatus, error) {
+	return entities.UpdaterStatus{
+		LastAttempt: lastSuccess,
+		LastSuccess: lastSuccess,
+		NextRun:     lastSuccess.Add(time.Hour),
+		Books:       12,
+		Locked:      2,
+		Categories:  3,
+	}, nil
+}
+
+func request(t *testing.T, handler http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRefresh(t *testing.T) {
+	var refreshes atomic.Int64
+	router := NewRouter(testToken, func() { refreshes.Add(1) }, getStatus)
+
+	w := request(t, router, http.MethodPost, "/refresh", testToken)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, int64(1), refreshes.Load())
+
+	var status Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, 12, status.Books)
+	require.NotNil(t, status.NextRun)
+	assert.Equal(t, lastSuccess.Add(time.Hour), *status.NextRun)
+
+	w = request(t, router, http.MethodGet, "/refresh", testToken)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, int64(1), refreshes.Load())
+}
+
+func TestRequiresToken(t *testing.T) {
+	var refreshes atomic.Int64
+	router := NewRouter(testToken, func() { refreshes.Add(1) }, getStatus)
+
+	for name, token := range map[string]string{"missing": "", "wrong": "guess"} {
+		t.Run(name, func(t *testing.T) {
+			w := request(t, router, http.MethodPost, "/refresh", token)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, `Bearer realm="admin"`, w.Header().Get("WWW-Authenticate"))
+			assert.JSONEq(t, `{"error":{"code":"unauthorized","message":"a valid token is required"}}`, w.Body.String())
+
+			w = request(t, router, http.MethodGet, "/status", token)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+	assert.Zero(t, refreshes.Load())
+}
+
+func TestStatus(t *testing.T) {
+	router := NewRouter(testToken, func() {}, getStatus)
+
+	w := request(t, router, http.MethodGet, "/status", testToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"running": false,
+		"lastAttempt": "2025-01-01T10:00:00Z",
+		"lastSuccess": "2025-01-01T10:00:00Z",
+		"failures": 0,
+		"nextRun": "2025-01-01T11:00:00Z",
+		"books": 12,
+		"locked": 2,
+		"categories": 3
+	}`, w.Body.String())
+}
+
+func TestStatusError(t *testing.T) {
+	router := NewRouter(testToken, func() {}, func(context.Context) (entities.UpdaterStatus, error) {
+		return entities.UpdaterStatus{}, errors.New("boom")
+	})
+
+	w := request(t, router, http.MethodGet, "/status", testToken)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,50 @@
+// Package api implements the versioned JSON API of the bookshelf.
+package api
+
+import (
+	_ "embed"
+	"log/slog"
+	"net/http"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/httpjson"
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// NewRouter creates the router for version 1 of the API.
+// It is meant to be mounted under /api/v1.
+func NewRouter(
+	getCategoriesFn handlers.GetCategoriesFn,
+	getBookFn handlers.GetBookFn,
+	getBooksFn handlers.GetBooksFn,
+	searchBooksFn handlers.SearchBooksFn,
+	getSourcesFn GetSourcesFn,
+	resolveAliasFn handlers.ResolveAliasFn,
+) chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/books", NewBooksHandler(getBooksFn, searchBooksFn).ServeHTTP)
+	r.With(handlers.RedirectAliases(resolveAliasFn, "bookID")).Get("/books/{bookID}", NewBookHandler(getBookFn).ServeHTTP)
+	r.Get("/categories", NewCategoriesHandler(getCategoriesFn).ServeHTTP)
+	r.Get("/sources", NewSourcesHandler(getSourcesFn).ServeHTTP)
+	r.Get("/openapi.json", serveOpenAPI)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		httpjson.WriteError(w, http.StatusNotFound, "not_found", "resource not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		httpjson.WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	})
+
+	return r
+}
+
+func serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPIDocument); err != nil {
+		slog.Error("cannot write OpenAPI document", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/bookshelf"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T) (chi.Router, []entities.Book) {
+	t.Helper()
+	storage := bookshelf.NewStorage()
+	require.NoError(t, storage.ReplaceAll(t.Context(), []entities.Book{
+		{
+			Title:        "MagPi 1",
+			Description:  "First issue",
+			Cover:        "http://localhost/covers/1",
+			Link:         "http://localhost/magpi/1",
+			Category:     "MagPI",
+			File:         "MagPi01.pdf",
+			Availability: entities.AvailabilityAvailable,
+			Issue:        1,
+			Year:         2012,
+			Month:        time.May,
+		},
+		{
+			Title:        "Book 1",
+			Cover:        "http://localhost/covers/book1",
+			Category:     "Book",
+			File:         "Book01.pdf",
+			Availability: entities.AvailabilityLocked,
+		},
+	}))
+
+	page, err := storage.Get(t.Context(), entities.BookQuery{})
+	require.NoError(t, err)
+	return NewRouter(storage.GetCategories, storage.GetByID, storage.Get, storage.Search, getSources, storage.ResolveAlias), page.Books
+}
+
+func getSources(context.Context) ([]entities.SourceHealth, error) {
+	return []entities.SourceHealth{
+		{Name: "magpi", Healthy: true, Books: 2, LastSuccess: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "nas", LastError: "connection refused", LastErrorAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, nil
+}
+
+func get(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestListBooks(t *testing.T) {
+	router, books := newTestRouter(t)
+
+	w := get(t, router, "/books")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"books": [
+			{
+				"id": "`+books[0].ID+`",
+				"title": "MagPi 1",
+				"description": "First issue",
+				"cover": "http://localhost/covers/1",
+				"link": "http://localhost/magpi/1",
+				"category": "MagPI",
+				"file": "MagPi01.pdf",
+				"availability": "available",
+				"issue": 1,
+				"year": 2012,
+				"month": 5,
+				"added": "`+books[0].Added.Format(time.RFC3339Nano)+`"
+			},
+			{
+				"id": "`+books[1].ID+`",
+				"title": "Book 1",
+				"description": "",
+				"cover": "http://localhost/covers/book1",
+				"link": "",
+				"category": "Book",
+				"file": "Book01.pdf",
+				"availability": "locked",
+				"added": "`+books[1].Added.Format(time.RFC3339Nano)+`"
+			}
+		],
+		"total": 2
+	}`, w.Body.String())
+}
+
+func TestListBooksByCategory(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	var result BookList
+	w := get(t, router, "/books?category=Book")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Books, 1)
+	assert.Equal(t, "Book 1", result.Books[0].Title)
+
+	w = get(t, router, "/books?category=Unknown")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"books": [], "total": 0}`, w.Body.String())
+}
+
+func TestListBooksByAvailability(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	var result BookList
+	w := get(t, router, "/books?availability=locked")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Books, 1)
+	assert.Equal(t, "Book 1", result.Books[0].Title)
+	assert.Equal(t, 1, result.Total)
+
+	w = get(t, router, "/books?availability=soon")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_parameter")
+}
+
+func TestListBooksSortedAndPaged(t *testing.T) {
+	router, books := newTestRouter(t)
+
+	var result BookList
+	w := get(t, router, "/books?sort=title&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Books, 1)
+	assert.Equal(t, "Book 1", result.Books[0].Title)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, books[1].ID, result.Next)
+
+	next := result.Next
+	result = BookList{}
+	w = get(t, router, "/books?sort=title&limit=1&cursor="+next)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Books, 1)
+	assert.Equal(t, "MagPi 1", result.Books[0].Title)
+	assert.Empty(t, result.Next)
+}
+
+func TestListBooksInvalidParameters(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	for _, target := range []string{
+		"/books?sort=price",
+		"/books?sort=title&order=random",
+		"/books?limit=0",
+		"/books?limit=ten",
+		"/books?limit=1&cursor=removed",
+	} {
+		w := get(t, router, target)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Contains(t, w.Body.String(), "invalid_parameter", target)
+	}
+}
+
+func TestSearchBooks(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	var result BookList
+	w := get(t, router, "/books?q=first+issue")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Books, 1)
+	assert.Equal(t, "MagPi 1", result.Books[0].Title)
+
+	w = get(t, router, "/books?q=issue&category=Book")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"books": [], "total": 0}`, w.Body.String())
+}
+
+func TestGetBook(t *testing.T) {
+	router, books := newTestRouter(t)
+
+	var result Book
+	w := get(t, router, "/books/"+books[0].ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, NewBook(books[0]), result)
+
+	w = get(t, router, "/books/missing")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": {"code": "not_found", "message": "book not found"}}`, w.Body.String())
+}
+
+func TestListCategories(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w := get(t, router, "/categories")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"categories": ["Book", "MagPI"]}`, w.Body.String())
+}
+
+func TestListSources(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w := get(t, router, "/sources")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"sources": [
+			{"name": "magpi", "healthy": true, "books": 2, "lastSuccess": "2025-01-01T00:00:00Z"},
+			{"name": "nas", "healthy": false, "books": 0, "lastError": "connection refused", "lastErrorAt": "2025-01-01T00:00:00Z"}
+		]
+	}`, w.Body.String())
+}
+
+func TestUnknownRoute(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w := get(t, router, "/unknown")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": {"code": "not_found", "message": "resource not found"}}`, w.Body.String())
+}
+
+// TestOpenAPIDocumentsEveryRoute makes sure the served OpenAPI document is
+// valid JSON and stays in sync with the routes.
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w := get(t, router, "/openapi.json")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var document struct {
+		Paths map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route == "/openapi.json" {
+			return nil
+		}
+		assert.Contains(t, document.Paths, strings.TrimSuffix(route, "/"), "route is not documented")
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/httpjson"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// Book is the JSON representation of a book.
+	Book struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Cover       string `json:"cover"`
+		Link        string `json:"link"`
+		Category    string `json:"category"`
+		// File is the upstream file name, also known for locked books.
+		File         string `json:"file"`
+		Availability string `json:"availability"`
+		// Issue, Year, Month and Edition are omitted when unknown.
+		Issue   int `json:"issue,omitempty"`
+		Year    int `json:"year,omitempty"`
+		Month   int `json:"month,omitempty"`
+		Edition int `json:"edition,omitempty"`
+		// Added is when the book was first seen in the catalog.
+		Added time.Time `json:"added"`
+	}
+
+	// BookList is the response of the book list endpoint.
+	BookList struct {
+		Books []Book `json:"books"`
+		// Total is the number of books matching the filters, on every page.
+		Total int `json:"total"`
+		// Next is the cursor of the next page, omitted on the last one.
+		Next string `json:"next,omitempty"`
+	}
+
+	BooksHandler struct {
+		getBooksFn    handlers.GetBooksFn
+		searchBooksFn handlers.SearchBooksFn
+	}
+
+	BookHandler struct {
+		getBookFn handlers.GetBookFn
+	}
+)
+
+// NewBook converts an entities.Book to its JSON representation.
+func NewBook(b entities.Book) Book {
+	return Book{
+		ID:           b.ID,
+		Title:        b.Title,
+		Description:  b.Description,
+		Cover:        b.Cover,
+		Link:         b.Link,
+		Category:     b.Category,
+		File:         b.File,
+		Availability: string(b.Availability),
+		Issue:        b.Issue,
+		Year:         b.Year,
+		Month:        int(b.Month),
+		Edition:      b.Edition,
+		Added:        b.Added,
+	}
+}
+
+// NewBooksHandler creates a new BooksHandler with the provided GetBooksFn and SearchBooksFn.
+// This handler lists the books, optionally filtered by the category query parameter.
+// When the q query parameter is given, the matching books are listed, best match first.
+// The availability query parameter only keeps the available or the locked books.
+// The sort and order query parameters sort the books, the limit query parameter
+// pages them, the next pages are requested with the cursor query parameter.
+func NewBooksHandler(getBooks handlers.GetBooksFn, searchBooks handlers.SearchBooksFn) *BooksHandler {
+	return &BooksHandler{
+		getBooksFn:    getBooks,
+		searchBooksFn: searchBooks,
+	}
+}
+
+func (h *BooksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	text := strings.TrimSpace(params.Get("q"))
+	q := entities.BookQuery{
+		Category: params.Get("category"),
+		Cursor:   params.Get("cursor"),
+	}
+
+	var ok bool
+	if q.Availability, ok = entities.ParseAvailability(params.Get("availability")); !ok && params.Get("availability") != "" {
+		httpjson.WriteError(w, http.StatusBadRequest, "invalid_parameter", "availability must be available or locked")
+		return
+	}
+	if q.Sort, ok = entities.ParseSortField(params.Get("sort")); !ok {
+		httpjson.WriteError(w, http.StatusBadRequest, "invalid_parameter", "sort must be title, issue or added")
+		return
+	}
+	if q.Order, ok = entities.ParseSortOrder(params.Get("order")); !ok {
+		httpjson.WriteError(w, http.StatusBadRequest, "invalid_parameter", "order must be asc or desc")
+		return
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			httpjson.WriteError(w, http.StatusBadRequest, "invalid_parameter", "limit must be a positive integer")
+			return
+		}
+		q.Limit = limit
+	}
+
+	var (
+		page entities.BookPage
+		err  error
+	)
+	if text != "" {
+		page, err = h.searchBooksFn(r.Context(), text, q)
+	} else {
+		page, err = h.getBooksFn(r.Context(), q)
+	}
+	if errors.Is(err, entities.ErrUnknownCursor) {
+		httpjson.WriteError(w, http.StatusBadRequest, "invalid_parameter", "cursor is unknown, the list must be requested again")
+		return
+	}
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "internal", "error fetching books")
+		return
+	}
+
+	result := BookList{
+		Books: make([]Book, 0, len(page.Books)),
+		Total: page.Total,
+		Next:  page.Next,
+	}
+	for _, b := range page.Books {
+		result.Books = append(result.Books, NewBook(b))
+	}
+	httpjson.Write(w, http.StatusOK, result)
+}
+
+// NewBookHandler creates a new BookHandler with the provided GetBookFn.
+// This handler returns a single book by its ID.
+func NewBookHandler(getBook handlers.GetBookFn) *BookHandler {
+	return &BookHandler{
+		getBookFn: getBook,
+	}
+}
+
+func (h *BookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bookID := chi.URLParam(r, "bookID")
+	book, err := h.getBookFn(r.Context(), bookID)
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "internal", "error fetching book")
+		return
+	}
+
+	if book == nil {
+		httpjson.WriteError(w, http.StatusNotFound, "not_found", "book not found")
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, NewBook(*book))
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/httpjson"
+)
+
+type (
+	// CategoryList is the response of the category list endpoint.
+	CategoryList struct {
+		Categories []string `json:"categories"`
+	}
+
+	CategoriesHandler struct {
+		getCategoriesFn handlers.GetCategoriesFn
+	}
+)
+
+// NewCategoriesHandler creates a new CategoriesHandler with the provided GetCategoriesFn.
+// This handler lists every category in the bookshelf.
+func NewCategoriesHandler(getCategories handlers.GetCategoriesFn) *CategoriesHandler {
+	return &CategoriesHandler{
+		getCategoriesFn: getCategories,
+	}
+}
+
+func (h *CategoriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.getCategoriesFn(r.Context())
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "internal", "error fetching categories")
+		return
+	}
+
+	if categories == nil {
+		categories = []string{}
+	}
+	httpjson.Write(w, http.StatusOK, CategoryList{Categories: categories})
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/httpjson"
+)
+
+type (
+	GetSourcesFn = func(ctx context.Context) ([]entities.SourceHealth, error)
+
+	// Source is the JSON representation of the health of a catalog source.
+	Source struct {
+		Name        string     `json:"name"`
+		Healthy     bool       `json:"healthy"`
+		Books       int        `json:"books"`
+		LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+		LastError   string     `json:"lastError,omitempty"`
+		LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	}
+
+	// SourceList is the response of the source list endpoint.
+	SourceList struct {
+		Sources []Source `json:"sources"`
+	}
+
+	SourcesHandler struct {
+		getSourcesFn GetSourcesFn
+	}
+)
+
+// NewSource converts an entities.SourceHealth to its JSON representation.
+func NewSource(s entities.SourceHealth) Source {
+	result := Source{
+		Name:      s.Name,
+		Healthy:   s.Healthy,
+		Books:     s.Books,
+		LastError: s.LastError,
+	}
+	if !s.LastSuccess.IsZero() {
+		result.LastSuccess = &s.LastSuccess
+	}
+	if !s.LastErrorAt.IsZero() {
+		result.LastErrorAt = &s.LastErrorAt
+	}
+	return result
+}
+
+// NewSourcesHandler creates a new SourcesHandler with the provided GetSourcesFn.
+// This handler lists the catalog sources and the result of their last refresh.
+func NewSourcesHandler(getSources GetSourcesFn) *SourcesHandler {
+	return &SourcesHandler{
+		getSourcesFn: getSources,
+	}
+}
+
+func (h *SourcesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sources, err := h.getSourcesFn(r.Context())
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "internal", "error fetching sources")
+		return
+	}
+
+	result := SourceList{Sources: make([]Source, 0, len(sources))}
+	for _, s := range sources {
+		result.Sources = append(result.Sources, NewSource(s))
+	}
+	httpjson.Write(w, http.StatusOK, result)
+}
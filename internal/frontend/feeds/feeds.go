@@ -0,0 +1,194 @@
+// Package feeds publishes the changes of the catalog as Atom and RSS feeds,
+// so new issues show up in feed readers.
+package feeds
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	feedTitle = "Raspberry Bookshelf"
+	// feedSize is the number of changes listed in a feed.
+	feedSize = 50
+)
+
+// GetChangesFn returns up to limit changes, newest first, optionally
+// filtered by category.
+type GetChangesFn = func(ctx context.Context, category string, limit int) ([]entities.Change, error)
+
+// NewRouter creates the router serving the feeds.
+// It is meant to be mounted under /feeds.
+func NewRouter(getChangesFn GetChangesFn) chi.Router {
+	r := chi.NewRouter()
+	h := NewFeedHandler(getChangesFn)
+	r.Get("/changes.atom", h.ServeAtom)
+	r.Get("/changes.rss", h.ServeRSS)
+	return r
+}
+
+// FeedHandler serves the changes of the catalog.
+type FeedHandler struct {
+	getChangesFn GetChangesFn
+}
+
+// NewFeedHandler creates a new FeedHandler with the provided function.
+func NewFeedHandler(getChanges GetChangesFn) *FeedHandler {
+	return &FeedHandler{
+		getChangesFn: getChanges,
+	}
+}
+
+// ServeAtom serves the changes as an Atom feed.
+func (h *FeedHandler) ServeAtom(w http.ResponseWriter, r *http.Request) {
+	category, changes, ok := h.changes(w, r)
+	if !ok {
+		return
+	}
+
+	base := handlers.BaseURL(r)
+	self := base + feedPath("/feeds/changes.atom", category)
+	updated := time.Now().UTC()
+	if len(changes) > 0 {
+		updated = changes[0].At
+	}
+
+	f := atomFeed{
+		ID:      self,
+		Title:   title(category),
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomAuthor{Name: feedTitle},
+		Links: []atomLink{
+			{Rel: "self", Href: self, Type: "application/atom+xml"},
+			{Rel: "alternate", Href: base + homePath(category), Type: "text/html"},
+		},
+		Entries: make([]atomEntry, 0, len(changes)),
+	}
+	for _, c := range changes {
+		f.Entries = append(f.Entries, atomEntry{
+			ID:       changeURN(c),
+			Title:    changeTitle(c),
+			Updated:  c.At.Format(time.RFC3339),
+			Summary:  c.Book.Description,
+			Link:     atomLink{Rel: "alternate", Href: base + changeLink(c)},
+			Category: atomCategory{Term: c.Book.Category},
+		})
+	}
+
+	write(w, "application/atom+xml; charset=utf-8", f)
+}
+
+// ServeRSS serves the changes as an RSS 2.0 feed.
+func (h *FeedHandler) ServeRSS(w http.ResponseWriter, r *http.Request) {
+	category, changes, ok := h.changes(w, r)
+	if !ok {
+		return
+	}
+
+	base := handlers.BaseURL(r)
+	channel := rssChannel{
+		Title:       title(category),
+		Link:        base + homePath(category),
+		Description: "Magazines and books added to the bookshelf",
+		AtomLink: rssAtomLink{
+			Href: base + feedPath("/feeds/changes.rss", category),
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+		Items: make([]rssItem, 0, len(changes)),
+	}
+	if len(changes) > 0 {
+		channel.LastBuildDate = changes[0].At.Format(time.RFC1123Z)
+	}
+	for _, c := range changes {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       changeTitle(c),
+			Link:        base + changeLink(c),
+			Description: c.Book.Description,
+			Category:    c.Book.Category,
+			GUID:        rssGUID{IsPermaLink: false, Value: changeURN(c)},
+			PubDate:     c.At.Format(time.RFC1123Z),
+		})
+	}
+
+	write(w, "application/rss+xml; charset=utf-8", rssFeed{
+		Version: "2.0",
+		Channel: channel,
+	})
+}
+
+// changes reads the changes requested by r. It writes an error response
+// and returns false if they cannot be fetched.
+func (h *FeedHandler) changes(w http.ResponseWriter, r *http.Request) (string, []entities.Change, bool) {
+	category := r.URL.Query().Get("cat")
+	changes, err := h.getChangesFn(r.Context(), category, feedSize)
+	if err != nil {
+		http.Error(w, "Error fetching changes", http.StatusInternalServerError)
+		return "", nil, false
+	}
+	return category, changes, true
+}
+
+func write(w http.ResponseWriter, contentType string, v any) {
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		slog.Error("cannot write feed", slog.Any("error", err))
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("cannot encode feed", slog.Any("error", err))
+	}
+}
+
+func feedPath(path, category string) string {
+	if category == "" {
+		return path
+	}
+	return path + "?cat=" + url.QueryEscape(category)
+}
+
+func homePath(category string) string {
+	return feedPath("/", category)
+}
+
+func title(category string) string {
+	if category == "" {
+		return feedTitle
+	}
+	return fmt.Sprintf("%s - %s", feedTitle, category)
+}
+
+func changeTitle(c entities.Change) string {
+	switch c.Kind {
+	case entities.ChangeAdded:
+		return "New: " + c.Book.Title
+	case entities.ChangeUnlocked:
+		return "Now available: " + c.Book.Title
+	case entities.ChangeRemoved:
+		return "Removed: " + c.Book.Title
+	default:
+		return c.Book.Title
+	}
+}
+
+// changeLink points to the PDF of the book, or to its category when there
+// is nothing to download.
+func changeLink(c entities.Change) string {
+	if c.Kind != entities.ChangeRemoved && c.Book.Link != "" {
+		return "/download/" + url.PathEscape(c.Book.ID)
+	}
+	return homePath(c.Book.Category)
+}
+
+func changeURN(c entities.Change) string {
+	return fmt.Sprintf("urn:bookshelf:change:%s:%s:%d", c.Kind, c.Book.ID, c.At.Unix())
+}
@@ -0,0 +1,102 @@
+package feeds
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testChanges = []entities.Change{
+	{
+		Kind: entities.ChangeAdded,
+		Book: entities.Book{ID: "issue-2", Title: "Issue 2", Description: "Second issue", Category: "MagPI"},
+		At:   time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC),
+	},
+	{
+		Kind: entities.ChangeUnlocked,
+		Book: entities.Book{ID: "issue-1", Title: "Issue 1", Link: "http://localhost/1.pdf", Category: "MagPI"},
+		At:   time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+	},
+}
+
+// fakeChanges returns the test changes and records the requested category.
+type fakeChanges struct {
+	category string
+	err      error
+}
+
+func (f *fakeChanges) get(_ context.Context, category string, limit int) ([]entities.Change, error) {
+	f.category = category
+	return testChanges, f.err
+}
+
+func get(t *testing.T, changes *fakeChanges, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = "bookshelf.local"
+	NewRouter(changes.get).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAtomFeed(t *testing.T) {
+	changes := &fakeChanges{}
+	rec := get(t, changes, "/changes.atom?cat=MagPI")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "MagPI", changes.category)
+
+	var f atomFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &f))
+	assert.Equal(t, "Raspberry Bookshelf - MagPI", f.Title)
+	assert.Equal(t, "2025-02-01T10:00:00Z", f.Updated)
+	require.Len(t, f.Entries, 2)
+
+	assert.Equal(t, "New: Issue 2", f.Entries[0].Title)
+	assert.Equal(t, "http://bookshelf.local/?cat=MagPI", f.Entries[0].Link.Href, "nothing to download yet")
+	assert.Equal(t, "Second issue", f.Entries[0].Summary)
+
+	assert.Equal(t, "Now available: Issue 1", f.Entries[1].Title)
+	assert.Equal(t, "http://bookshelf.local/download/issue-1", f.Entries[1].Link.Href)
+	assert.NotEqual(t, f.Entries[0].ID, f.Entries[1].ID)
+}
+
+func TestRSSFeed(t *testing.T) {
+	rec := get(t, &fakeChanges{}, "/changes.rss")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/rss+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var f rssFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &f))
+	assert.Equal(t, "2.0", f.Version)
+	assert.Equal(t, "Raspberry Bookshelf", f.Channel.Title)
+	assert.Equal(t, "http://bookshelf.local/feeds/changes.rss", f.Channel.AtomLink.Href)
+	require.Len(t, f.Channel.Items, 2)
+	assert.Equal(t, "Sat, 01 Feb 2025 10:00:00 +0000", f.Channel.Items[0].PubDate)
+	assert.False(t, f.Channel.Items[0].GUID.IsPermaLink)
+}
+
+func TestFeedUsesForwardedProto(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/changes.rss", nil)
+	req.Host = "bookshelf.example"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	NewRouter((&fakeChanges{}).get).ServeHTTP(rec, req)
+
+	var f rssFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &f))
+	assert.Equal(t, "https://bookshelf.example/", f.Channel.Link)
+}
+
+func TestFeedError(t *testing.T) {
+	rec := get(t, &fakeChanges{err: errors.New("boom")}, "/changes.atom")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
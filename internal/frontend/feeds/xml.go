@@ -0,0 +1,75 @@
+package feeds
+
+import "encoding/xml"
+
+type (
+	atomFeed struct {
+		XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string      `xml:"id"`
+		Title   string      `xml:"title"`
+		Updated string      `xml:"updated"`
+		Author  atomAuthor  `xml:"author"`
+		Links   []atomLink  `xml:"link"`
+		Entries []atomEntry `xml:"entry"`
+	}
+
+	atomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	atomLink struct {
+		Rel  string `xml:"rel,attr,omitempty"`
+		Href string `xml:"href,attr"`
+		Type string `xml:"type,attr,omitempty"`
+	}
+
+	atomEntry struct {
+		ID       string       `xml:"id"`
+		Title    string       `xml:"title"`
+		Updated  string       `xml:"updated"`
+		Summary  string       `xml:"summary,omitempty"`
+		Link     atomLink     `xml:"link"`
+		Category atomCategory `xml:"category"`
+	}
+
+	atomCategory struct {
+		Term string `xml:"term,attr"`
+	}
+
+	rssFeed struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+
+	rssChannel struct {
+		// AtomLink comes before Link, so decoding does not mistake one for the other.
+		AtomLink      rssAtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Title         string      `xml:"title"`
+		Link          string      `xml:"link"`
+		Description   string      `xml:"description"`
+		LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+		Items         []rssItem   `xml:"item"`
+	}
+
+	// rssAtomLink is the self link recommended by the RSS validators.
+	rssAtomLink struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	}
+
+	rssItem struct {
+		Title       string  `xml:"title"`
+		Link        string  `xml:"link"`
+		Description string  `xml:"description,omitempty"`
+		Category    string  `xml:"category,omitempty"`
+		GUID        rssGUID `xml:"guid"`
+		PubDate     string  `xml:"pubDate"`
+	}
+
+	rssGUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	}
+)
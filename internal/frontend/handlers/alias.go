@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ResolveAliasFn returns the current ID of a book that was known under another ID.
+type ResolveAliasFn = func(ctx context.Context, bookID string) (string, bool)
+
+// RedirectAliases redirects the requests for a former ID of a book, found in
+// the given URL parameter, to the same URL with its current ID. It must be
+// used on routes, with chi.Router.With, so the URL parameters are known.
+func RedirectAliases(resolveAlias ResolveAliasFn, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bookID := chi.URLParam(r, param)
+			if bookID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			current, ok := resolveAlias(r.Context(), bookID)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			escaped := url.PathEscape(bookID)
+			segments := strings.Split(r.URL.EscapedPath(), "/")
+			for i, segment := range segments {
+				if segment == escaped {
+					segments[i] = url.PathEscape(current)
+					break
+				}
+			}
+			target := strings.Join(segments, "/")
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func aliasRouter() *chi.Mux {
+	aliases := map[string]string{"old id": "new"}
+	resolveAlias := func(_ context.Context, id string) (string, bool) {
+		current, ok := aliases[id]
+		return current, ok
+	}
+
+	r := chi.NewRouter()
+	r.With(RedirectAliases(resolveAlias, "bookID")).Get("/download/{bookID}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(chi.URLParam(r, "bookID")))
+	})
+	return r
+}
+
+func TestRedirectAliases(t *testing.T) {
+	w := httptest.NewRecorder()
+	aliasRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download/old%20id?x=1", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/download/new?x=1", w.Header().Get("Location"))
+}
+
+func TestRedirectAliasesServesCurrentIDs(t *testing.T) {
+	w := httptest.NewRecorder()
+	aliasRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download/new", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "new", w.Body.String())
+}
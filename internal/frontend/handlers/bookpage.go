@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/covers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/templates"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/templates/modules"
+	"github.com/go-chi/chi/v5"
+)
+
+// BookPageHandler serves the page of a book.
+type BookPageHandler struct {
+	getBookFn       GetBookFn
+	getCategoriesFn GetCategoriesFn
+}
+
+// NewBookPageHandler creates a new BookPageHandler with the provided GetBookFn and GetCategoriesFn.
+// This handler renders the details of a book as a full page, with the
+// metadata used by the link previews of chat applications and social
+// networks. Requests without the slug of the title, or with an outdated one,
+// are redirected to the permalink.
+func NewBookPageHandler(getBook GetBookFn, getCategories GetCategoriesFn) *BookPageHandler {
+	return &BookPageHandler{
+		getBookFn:       getBook,
+		getCategoriesFn: getCategories,
+	}
+}
+
+func (h *BookPageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bookID := chi.URLParam(r, "bookID")
+	book, err := h.getBookFn(r.Context(), bookID)
+	if err != nil {
+		http.Error(w, "Error fetching book", http.StatusInternalServerError)
+		return
+	}
+
+	if book == nil {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+
+	permalink := modules.BookURL(book)
+	if r.URL.EscapedPath() != permalink {
+		http.Redirect(w, r, permalink, http.StatusMovedPermanently)
+		return
+	}
+
+	categories, err := h.getCategoriesFn(r.Context())
+	if err != nil {
+		slog.Error("cannot get list of categories", slog.Any("error", err))
+	}
+
+	base := BaseURL(r)
+	meta := templates.PageMeta{
+		Description: book.Description,
+		URL:         base + permalink,
+		Image:       base + covers.URL(*book, covers.SizeLarge),
+		Type:        "book",
+	}
+	c := modules.BookPage(book)
+
+	err = templates.Layout(c, book.Title+" - Bookshelf", meta, book.Category, "", "", categories).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// BaseURL returns the scheme and host the request was sent to, link
+// previews and feed readers need absolute URLs.
+func BaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
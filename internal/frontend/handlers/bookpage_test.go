@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bookPageRouter() *chi.Mux {
+	books := map[string]*entities.Book{
+		"abc": {
+			ID:          "abc",
+			Title:       "The MagPi #150: Build a Pi 5 Retro Console!",
+			Description: "Play the classics & more",
+			Cover:       "http://localhost/covers/150.jpg",
+			Link:        "http://localhost/150.pdf",
+			Category:    "MagPI",
+			Issue:       150,
+			Year:        2025,
+			Month:       time.February,
+		},
+	}
+	getBook := func(_ context.Context, id string) (*entities.Book, error) {
+		return books[id], nil
+	}
+	getCategories := func(context.Context) ([]string, error) {
+		return []string{"MagPI"}, nil
+	}
+
+	h := NewBookPageHandler(getBook, getCategories)
+	r := chi.NewRouter()
+	r.Get("/book/{bookID}", h.ServeHTTP)
+	r.Get("/book/{bookID}/{slug}", h.ServeHTTP)
+	return r
+}
+
+func TestBookPage(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/book/abc/the-magpi-150-build-a-pi-5-retro-console", nil)
+	req.Host = "bookshelf.local"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	bookPageRouter().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<title>The MagPi #150: Build a Pi 5 Retro Console! - Bookshelf</title>")
+	assert.Contains(t, body, `<link rel="canonical" href="https://bookshelf.local/book/abc/the-magpi-150-build-a-pi-5-retro-console">`)
+	assert.Contains(t, body, `<meta property="og:type" content="book">`)
+	assert.Contains(t, body, `<meta property="og:description" content="Play the classics &amp; more">`)
+	assert.Contains(t, body, `<meta property="og:image" content="https://bookshelf.local/covers/abc/large?v=`)
+	assert.Contains(t, body, `<meta name="twitter:card" content="summary_large_image">`)
+	assert.Contains(t, body, "Issue 150 · February 2025")
+	assert.Contains(t, body, `href="/download/abc"`, "the book can be downloaded without JavaScript")
+}
+
+func TestBookPageRedirectsToPermalink(t *testing.T) {
+	for _, path := range []string{"/book/abc", "/book/abc/old-title"} {
+		w := httptest.NewRecorder()
+		bookPageRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code, path)
+		assert.Equal(t, "/book/abc/the-magpi-150-build-a-pi-5-retro-console", w.Header().Get("Location"), path)
+	}
+}
+
+func TestBookPageNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	bookPageRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/book/missing/title", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -2,39 +2,108 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
 	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/templates/modules"
 )
 
 type (
-	GetBooksFn   = func(ctx context.Context, category string) ([]entities.Book, error)
-	BooksHandler struct {
-		getBooksFn GetBooksFn
+	GetBooksFn    = func(ctx context.Context, q entities.BookQuery) (entities.BookPage, error)
+	SearchBooksFn = func(ctx context.Context, text string, q entities.BookQuery) (entities.BookPage, error)
+	BooksHandler  struct {
+		getBooksFn    GetBooksFn
+		searchBooksFn SearchBooksFn
+		itemsOnly     bool
 	}
 )
 
-// NewBooksHandler creates a new BooksHandler with the provided GetBooksFn.
+// booksPageSize is the number of books loaded at once by the grid.
+const booksPageSize = 48
+
+// NewBooksHandler creates a new BooksHandler with the provided GetBooksFn and SearchBooksFn.
 // This handler is responsible for serving a list of books, optionally filtered by category.
+// When a search query is given, the matching books are listed instead, best match first.
+// The "Coming soon" filter only keeps the locked books.
+// The books are sorted by the sort and order query parameters and served a page at a
+// time, from the book after the cursor query parameter, or from the first book when
+// the cursor is out of date.
 // It returns a component that can be displayed on a page.
-func NewBooksHandler(getBooks GetBooksFn) *BooksHandler {
+func NewBooksHandler(getBooks GetBooksFn, searchBooks SearchBooksFn) *BooksHandler {
+	return &BooksHandler{
+		getBooksFn:    getBooks,
+		searchBooksFn: searchBooks,
+	}
+}
+
+// NewBookItemsHandler creates a new BooksHandler which only renders the books of the
+// page, without the sort controls and the grid, so they can be appended to the grid
+// while scrolling.
+func NewBookItemsHandler(getBooks GetBooksFn, searchBooks SearchBooksFn) *BooksHandler {
 	return &BooksHandler{
-		getBooksFn: getBooks,
+		getBooksFn:    getBooks,
+		searchBooksFn: searchBooks,
+		itemsOnly:     true,
 	}
 }
 
 func (h *BooksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	currentCategory := r.URL.Query().Get("cat")
-	books, err := h.getBooksFn(r.Context(), currentCategory)
+	filter := parseBooksFilter(r)
+	q := entities.BookQuery{
+		Category:     filter.Category,
+		Availability: filter.Availability,
+		Sort:         filter.Sort,
+		Order:        filter.Order,
+		Limit:        booksPageSize,
+		Cursor:       r.URL.Query().Get("cursor"),
+	}
+
+	page, err := h.books(r.Context(), filter, q)
+	if errors.Is(err, entities.ErrUnknownCursor) {
+		if h.itemsOnly {
+			// the catalog changed while scrolling, the grid simply ends here
+			return
+		}
+		// the link is out of date, the books are listed from the first one
+		q.Cursor = ""
+		page, err = h.books(r.Context(), filter, q)
+	}
 	if err != nil {
 		http.Error(w, "Error fetching books", http.StatusInternalServerError)
 		return
 	}
 
-	c := modules.Books(books)
+	c := modules.Books(filter, page)
+	if h.itemsOnly {
+		c = modules.BookItems(filter, page, r.URL.Query().Get("group"))
+	}
 	err = c.Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering template", http.StatusInternalServerError)
 	}
 }
+
+// books returns the page of books of the query, the books matching the
+// search text when there is one.
+func (h *BooksHandler) books(ctx context.Context, filter modules.BooksFilter, q entities.BookQuery) (entities.BookPage, error) {
+	if filter.Query != "" {
+		return h.searchBooksFn(ctx, filter.Query, q)
+	}
+	return h.getBooksFn(ctx, q)
+}
+
+// parseBooksFilter returns the filter of the books from the query string.
+// Unknown values are ignored.
+func parseBooksFilter(r *http.Request) modules.BooksFilter {
+	params := r.URL.Query()
+	filter := modules.BooksFilter{
+		Category: params.Get("cat"),
+		Query:    strings.TrimSpace(params.Get("q")),
+	}
+	filter.Availability, _ = entities.ParseAvailability(params.Get("avail"))
+	filter.Sort, _ = entities.ParseSortField(params.Get("sort"))
+	filter.Order, _ = entities.ParseSortOrder(params.Get("order"))
+	return filter
+}
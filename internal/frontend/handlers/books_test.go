@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBooksPages(t *testing.T) {
+	var queries []entities.BookQuery
+	getBooks := func(_ context.Context, q entities.BookQuery) (entities.BookPage, error) {
+		queries = append(queries, q)
+		if q.Cursor == "removed" {
+			return entities.BookPage{}, entities.ErrUnknownCursor
+		}
+		page := entities.BookPage{Total: 100}
+		for i := range q.Limit {
+			page.Books = append(page.Books, entities.Book{
+				ID:    fmt.Sprintf("book-%d", i),
+				Title: "Book",
+				Issue: 200 - i,
+				Year:  2025 - i/12,
+			})
+		}
+		page.Next = page.Books[len(page.Books)-1].ID
+		return page, nil
+	}
+	h := NewBooksHandler(getBooks, nil)
+	items := NewBookItemsHandler(getBooks, nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/module/books?cat=MagPI&sort=issue&order=asc", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `class="books-toolbar"`)
+	assert.Contains(t, body, `class="books-grid"`)
+	assert.Contains(t, body, `href="/?cat=MagPI&amp;cursor=book-47&amp;order=asc&amp;sort=issue"`)
+	assert.Contains(t, body, `hx-get="/module/books/items?cat=MagPI&amp;cursor=book-47&amp;group=2022&amp;order=asc&amp;sort=issue"`)
+	assert.Contains(t, body, `hx-trigger="revealed"`)
+	assert.Contains(t, body, `<h2 class="books-group">2025</h2>`)
+	assert.Contains(t, body, `<h2 class="books-group">2022</h2>`)
+	assert.Equal(t, entities.BookQuery{
+		Category: "MagPI",
+		Sort:     entities.SortIssue,
+		Order:    entities.OrderAsc,
+		Limit:    booksPageSize,
+	}, queries[0])
+
+	w = httptest.NewRecorder()
+	items.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/module/books/items?cat=MagPI&sort=issue&order=asc&cursor=book-47&group=2025", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.NotContains(t, body, `class="books-grid"`, "the next pages only render their books")
+	assert.NotContains(t, body, `class="books-toolbar"`)
+	assert.NotContains(t, body, `<h2 class="books-group">2025</h2>`, "the books continue the group of the previous page")
+	assert.Contains(t, body, `<h2 class="books-group">2024</h2>`)
+	assert.Contains(t, body, `hx-trigger="revealed"`)
+	assert.Equal(t, "book-47", queries[1].Cursor)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/module/books?cursor=book-47", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `class="books-grid"`, "the page after a cursor renders the whole module")
+	assert.NotContains(t, w.Body.String(), `class="books-group"`, "only the books sorted by issue are grouped")
+	assert.Equal(t, "book-47", queries[2].Cursor)
+
+	w = httptest.NewRecorder()
+	items.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/module/books/items?cursor=removed", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String(), "the grid ends when the catalog changed")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/module/books?cursor=removed", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `class="books-grid"`, "an out of date link lists the books from the first one")
+	assert.Equal(t, "removed", queries[4].Cursor)
+	assert.Empty(t, queries[5].Cursor)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/covers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// LookupCoverFn returns the path of the cached cover of a book, if there is one.
+	LookupCoverFn = func(ctx context.Context, book entities.Book, size covers.Size) (string, bool)
+	CoverHandler  struct {
+		getBookFn     GetBookFn
+		lookupCoverFn LookupCoverFn
+	}
+)
+
+// NewCoverHandler creates a new CoverHandler with the provided GetBookFn and LookupCoverFn.
+// This handler serves the cached cover of a book, with long-lived cache headers
+// when the URL has the version of the current cover, or redirects to the
+// upstream cover while it is not cached.
+func NewCoverHandler(getBook GetBookFn, lookupCover LookupCoverFn) *CoverHandler {
+	return &CoverHandler{
+		getBookFn:     getBook,
+		lookupCoverFn: lookupCover,
+	}
+}
+
+func (h *CoverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	size := covers.SizeThumbnail
+	if name := chi.URLParam(r, "size"); name != "" {
+		var ok bool
+		if size, ok = covers.ParseSize(name); !ok {
+			http.Error(w, "Unknown cover size", http.StatusNotFound)
+			return
+		}
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+	book, err := h.getBookFn(r.Context(), bookID)
+	if err != nil {
+		http.Error(w, "Error fetching book", http.StatusInternalServerError)
+		return
+	}
+
+	if book == nil || book.Cover == "" {
+		http.Error(w, "Cover not found", http.StatusNotFound)
+		return
+	}
+
+	if filePath, ok := h.lookupCoverFn(r.Context(), *book, size); ok {
+		err := serveCover(w, r, filePath, r.URL.Query().Get("v") == covers.Version(*book))
+		if err == nil {
+			return
+		}
+		slog.ErrorContext(r.Context(), "cannot open cached cover", slog.Any("error", err))
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	http.Redirect(w, r, book.Cover, http.StatusFound)
+}
+
+// serveCover serves the cached cover file. Only a versioned URL can be cached
+// forever, since its version changes with the upstream cover.
+func serveCover(w http.ResponseWriter, r *http.Request, filePath string, versioned bool) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			slog.Error("Cannot close cover file", slog.Any("error", err))
+		}
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if versioned {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+	return nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/covers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverCacheHeaders(t *testing.T) {
+	book := entities.Book{ID: "abc", Cover: "https://example.com/cover-v2.jpg"}
+	cached := filepath.Join(t.TempDir(), "abc.jpg")
+	require.NoError(t, os.WriteFile(cached, []byte("jpeg"), 0o644))
+
+	getBook := func(context.Context, string) (*entities.Book, error) { return &book, nil }
+	lookupCover := func(context.Context, entities.Book, covers.Size) (string, bool) { return cached, true }
+	r := chi.NewRouter()
+	r.Get("/covers/{bookID}/{size}", NewCoverHandler(getBook, lookupCover).ServeHTTP)
+
+	tests := []struct {
+		name         string
+		url          string
+		cacheControl string
+	}{
+		{name: "current version", url: covers.URL(book, covers.SizeLarge), cacheControl: "public, max-age=31536000, immutable"},
+		{name: "previous version", url: "/covers/abc/large?v=0badc0de", cacheControl: "no-cache"},
+		{name: "no version", url: "/covers/abc/large", cacheControl: "no-cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "jpeg", w.Body.String())
+			assert.Equal(t, tt.cacheControl, w.Header().Get("Cache-Control"))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// LookupMirroredFn returns the path of the local copy of a book PDF, if there is one.
+	LookupMirroredFn = func(ctx context.Context, bookID string) (string, bool)
+	DownloadHandler  struct {
+		getBookFn        GetBookFn
+		lookupMirroredFn LookupMirroredFn
+	}
+)
+
+// NewDownloadHandler creates a new DownloadHandler with the provided GetBookFn and LookupMirroredFn.
+// This handler serves the local copy of the book PDF when it was mirrored,
+// otherwise it redirects to the upstream link.
+func NewDownloadHandler(getBook GetBookFn, lookupMirrored LookupMirroredFn) *DownloadHandler {
+	return &DownloadHandler{
+		getBookFn:        getBook,
+		lookupMirroredFn: lookupMirrored,
+	}
+}
+
+func (h *DownloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bookID := chi.URLParam(r, "bookID")
+	book, err := h.getBookFn(r.Context(), bookID)
+	if err != nil {
+		http.Error(w, "Error fetching book", http.StatusInternalServerError)
+		return
+	}
+
+	if book == nil || book.Link == "" {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+
+	if filePath, ok := h.lookupMirroredFn(r.Context(), bookID); ok {
+		if h.serveFile(w, r, filePath, book.Link) {
+			return
+		}
+	}
+
+	http.Redirect(w, r, book.Link, http.StatusFound)
+}
+
+// serveFile serves the mirrored file, it returns false if the file cannot be opened.
+func (h *DownloadHandler) serveFile(w http.ResponseWriter, r *http.Request, filePath, link string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "cannot open mirrored file", slog.Any("error", err))
+		return false
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			slog.Error("Cannot close mirrored file", slog.Any("error", err))
+		}
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "cannot stat mirrored file", slog.Any("error", err))
+		return false
+	}
+
+	fileName := entities.FileName(link)
+	if fileName == "" {
+		fileName = "book.pdf"
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	http.ServeContent(w, r, "", info.ModTime(), f)
+	return true
+}
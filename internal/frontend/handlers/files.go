@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// LookupLocalFileFn returns the path of a file of a local source from its
+	// name in the URL, if the source serves it.
+	LookupLocalFileFn = func(ctx context.Context, source, name string) (string, bool)
+	LocalFileHandler  struct {
+		lookupLocalFileFn LookupLocalFileFn
+	}
+)
+
+// NewLocalFileHandler creates a new LocalFileHandler with the provided LookupLocalFileFn.
+// This handler serves the PDFs and covers of the local directory sources.
+func NewLocalFileHandler(lookupLocalFile LookupLocalFileFn) *LocalFileHandler {
+	return &LocalFileHandler{
+		lookupLocalFileFn: lookupLocalFile,
+	}
+}
+
+func (h *LocalFileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	source := chi.URLParam(r, "source")
+	name := chi.URLParam(r, "*")
+	if r.URL.RawPath != "" {
+		// chi matches the escaped path, so the parameters are still escaped
+		var err error
+		if source, err = url.PathUnescape(source); err == nil {
+			name, err = url.PathUnescape(name)
+		}
+		if err != nil {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	filePath, ok := h.lookupLocalFileFn(r.Context(), source, name)
+	if !ok {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "cannot open local file", slog.Any("error", err))
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			slog.Error("Cannot close local file", slog.Any("error", err))
+		}
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "cannot stat local file", slog.Any("error", err))
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if strings.EqualFold(path.Ext(name), ".pdf") {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)))
+	}
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
@@ -26,14 +26,14 @@ func NewIndexHandler(getCategories GetCategoriesFn) *IndexHandler {
 }
 
 func (h *IndexHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	currentCategory := r.URL.Query().Get("cat")
+	filter := parseBooksFilter(r)
 	categories, err := h.getCategoriesFn(r.Context())
 	if err != nil {
 		slog.Error("cannot get list of categories", slog.Any("error", err))
 	}
-	c := templates.PageIndex(currentCategory)
+	c := templates.PageIndex(filter, r.URL.Query().Get("cursor"))
 
-	err = templates.Layout(c, "Bookshelf", currentCategory, categories).Render(r.Context(), w)
+	err = templates.Layout(c, "Bookshelf", templates.PageMeta{}, filter.Category, filter.Query, filter.Availability, categories).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering template", http.StatusInternalServerError)
 		return
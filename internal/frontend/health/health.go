@@ -0,0 +1,135 @@
+// Package health implements the probes used by Docker and Kubernetes to
+// check that the application is alive and ready to serve the catalog.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/httpjson"
+)
+
+// The values of the status field of the probes.
+const (
+	StatusOK          = "ok"
+	StatusReady       = "ready"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+type (
+	// GetStatusFn returns the status of the catalog refreshes.
+	GetStatusFn = func(ctx context.Context) (entities.UpdaterStatus, error)
+	// GetSnapshotTimeFn returns when the persisted catalog loaded at startup
+	// was saved, or the zero time when none was loaded.
+	GetSnapshotTimeFn = func() time.Time
+
+	// Liveness is the JSON body of the liveness probe.
+	Liveness struct {
+		Status string `json:"status"`
+	}
+
+	// Readiness is the JSON body of the readiness probe.
+	Readiness struct {
+		Status string `json:"status"`
+		// Reason explains why the catalog is not ready or degraded.
+		Reason          string     `json:"reason,omitempty"`
+		Books           int        `json:"books"`
+		LastSuccess     *time.Time `json:"lastSuccess,omitempty"`
+		SnapshotSavedAt *time.Time `json:"snapshotSavedAt,omitempty"`
+		// AgeSeconds is the age of the catalog, from the last successful
+		// refresh or else from the persisted snapshot.
+		AgeSeconds        int64  `json:"ageSeconds"`
+		StaleAfterSeconds int64  `json:"staleAfterSeconds,omitempty"`
+		LastError         string `json:"lastError,omitempty"`
+		Failures          int    `json:"failures"`
+	}
+)
+
+// LivenessHandler tells that the process is alive.
+type LivenessHandler struct{}
+
+// NewLivenessHandler creates a new LivenessHandler.
+// It always succeeds, it only checks that the server answers.
+func NewLivenessHandler() *LivenessHandler {
+	return &LivenessHandler{}
+}
+
+func (h *LivenessHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Liveness{Status: StatusOK})
+}
+
+// ReadinessHandler tells whether the catalog can be served.
+type ReadinessHandler struct {
+	getStatusFn       GetStatusFn
+	getSnapshotTimeFn GetSnapshotTimeFn
+	staleAfter        time.Duration
+	now               func() time.Time
+}
+
+// NewReadinessHandler creates a new ReadinessHandler with the provided GetStatusFn and GetSnapshotTimeFn.
+// This handler fails until the catalog was refreshed or loaded from the
+// persisted snapshot. Once the catalog is older than staleAfter, it answers
+// that the catalog is degraded, which still succeeds since the last good
+// catalog is served. A zero staleAfter disables the check.
+func NewReadinessHandler(getStatus GetStatusFn, getSnapshotTime GetSnapshotTimeFn, staleAfter time.Duration) *ReadinessHandler {
+	return &ReadinessHandler{
+		getStatusFn:       getStatus,
+		getSnapshotTimeFn: getSnapshotTime,
+		staleAfter:        staleAfter,
+		now:               time.Now,
+	}
+}
+
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, err := h.getStatusFn(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "cannot get the updater status", slog.Any("error", err))
+		writeJSON(w, http.StatusServiceUnavailable, Readiness{
+			Status: StatusUnavailable,
+			Reason: "cannot get the status of the catalog",
+		})
+		return
+	}
+
+	snapshot := h.getSnapshotTimeFn()
+	readiness := Readiness{
+		Status:            StatusReady,
+		Books:             status.Books,
+		LastSuccess:       httpjson.OptionalTime(status.LastSuccess),
+		SnapshotSavedAt:   httpjson.OptionalTime(snapshot),
+		StaleAfterSeconds: int64(h.staleAfter.Seconds()),
+		LastError:         status.LastError,
+		Failures:          status.Failures,
+	}
+
+	updated := status.LastSuccess
+	if updated.IsZero() {
+		updated = snapshot
+	}
+	if updated.IsZero() {
+		readiness.Status = StatusUnavailable
+		readiness.Reason = "the catalog was not loaded yet"
+		writeJSON(w, http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	age := h.now().Sub(updated)
+	readiness.AgeSeconds = int64(age.Seconds())
+	if h.staleAfter > 0 && age > h.staleAfter {
+		readiness.Status = StatusDegraded
+		readiness.Reason = fmt.Sprintf("the catalog was not refreshed for more than %s", h.staleAfter)
+	}
+	writeJSON(w, http.StatusOK, readiness)
+}
+
+// writeJSON writes v as the JSON body of the response with the given status.
+// Probes must never be answered from a cache.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Cache-Control", "no-store")
+	httpjson.Write(w, status, v)
+}
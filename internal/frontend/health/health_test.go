@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func readiness(
+	t *testing.T,
+	status entities.UpdaterStatus,
+	statusErr error,
+	snapshot time.Time,
+	staleAfter time.Duration,
+) (int, Readiness) {
+	t.Helper()
+	handler := NewReadinessHandler(
+		func(context.Context) (entities.UpdaterStatus, error) { return status, statusErr },
+		func() time.Time { return snapshot },
+		staleAfter,
+	)
+	handler.now = func() time.Time { return now }
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var body Readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestLiveness(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewLivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadinessBeforeFirstLoad(t *testing.T) {
+	code, body := readiness(t, entities.UpdaterStatus{
+		LastError: "magpi is down",
+		Failures:  2,
+	}, nil, time.Time{}, time.Hour)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, body.Status)
+	assert.NotEmpty(t, body.Reason)
+	assert.Equal(t, "magpi is down", body.LastError)
+	assert.Equal(t, 2, body.Failures)
+}
+
+func TestReadinessFromSnapshot(t *testing.T) {
+	snapshot := now.Add(-30 * time.Minute)
+	code, body := readiness(t, entities.UpdaterStatus{Books: 12}, nil, snapshot, time.Hour)
+
+	assert.Equal(t, http.StatusOK, code, "the persisted catalog can be served")
+	assert.Equal(t, StatusReady, body.Status)
+	assert.Equal(t, 12, body.Books)
+	require.NotNil(t, body.SnapshotSavedAt)
+	assert.Equal(t, snapshot, *body.SnapshotSavedAt)
+	assert.Nil(t, body.LastSuccess)
+	assert.Equal(t, int64(1800), body.AgeSeconds)
+}
+
+func TestReadinessAfterRefresh(t *testing.T) {
+	lastSuccess := now.Add(-10 * time.Minute)
+	code, body := readiness(t, entities.UpdaterStatus{
+		LastSuccess: lastSuccess,
+		Books:       12,
+	}, nil, now.Add(-48*time.Hour), time.Hour)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusReady, body.Status)
+	assert.Empty(t, body.Reason)
+	assert.Equal(t, int64(600), body.AgeSeconds, "the age comes from the last refresh")
+	assert.Equal(t, int64(3600), body.StaleAfterSeconds)
+}
+
+func TestReadinessDegraded(t *testing.T) {
+	code, body := readiness(t, entities.UpdaterStatus{
+		LastSuccess: now.Add(-2 * time.Hour),
+		LastError:   "magpi is down",
+		Failures:    5,
+	}, nil, time.Time{}, time.Hour)
+
+	assert.Equal(t, http.StatusOK, code, "the last good catalog is still served")
+	assert.Equal(t, StatusDegraded, body.Status)
+	assert.Contains(t, body.Reason, "1h0m0s")
+	assert.Equal(t, int64(7200), body.AgeSeconds)
+	assert.Equal(t, 5, body.Failures)
+
+	_, body = readiness(t, entities.UpdaterStatus{
+		LastSuccess: now.Add(-2 * time.Hour),
+	}, nil, time.Time{}, 0)
+	assert.Equal(t, StatusReady, body.Status, "a zero threshold disables the check")
+}
+
+func TestReadinessStatusError(t *testing.T) {
+	code, body := readiness(t, entities.UpdaterStatus{}, errors.New("boom"), now, time.Hour)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, body.Status)
+}
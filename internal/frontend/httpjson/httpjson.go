@@ -0,0 +1,48 @@
+// Package httpjson writes the JSON responses shared by the API, the admin
+// and the health endpoints.
+package httpjson
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ErrorResponse is the body of every error returned by the JSON endpoints.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Write writes v as the JSON body of the response with the given status.
+func Write(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("cannot encode JSON response", slog.Any("error", err))
+	}
+}
+
+// WriteError writes an error response with the given status.
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	Write(w, status, ErrorResponse{
+		Error: ErrorDetail{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+// OptionalTime returns nil for the zero time, so it is left out of the
+// response instead of being written as year 1.
+func OptionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,217 @@
+package opds
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+)
+
+const (
+	navigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	acquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	openSearchType  = "application/opensearchdescription+xml"
+
+	relAcquisition = "http://opds-spec.org/acquisition"
+	relImage       = "http://opds-spec.org/image"
+	relThumbnail   = "http://opds-spec.org/image/thumbnail"
+)
+
+type (
+	feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Title   string   `xml:"title"`
+		Updated string   `xml:"updated"`
+		Author  author   `xml:"author"`
+		Links   []link   `xml:"link"`
+		Entries []entry  `xml:"entry"`
+	}
+
+	author struct {
+		Name string `xml:"name"`
+	}
+
+	link struct {
+		Rel   string `xml:"rel,attr,omitempty"`
+		Href  string `xml:"href,attr"`
+		Type  string `xml:"type,attr,omitempty"`
+		Title string `xml:"title,attr,omitempty"`
+	}
+
+	entry struct {
+		ID         string    `xml:"id"`
+		Title      string    `xml:"title"`
+		Updated    string    `xml:"updated"`
+		Content    *content  `xml:"content,omitempty"`
+		Categories []atomCat `xml:"category,omitempty"`
+		Links      []link    `xml:"link"`
+	}
+
+	content struct {
+		Type string `xml:"type,attr"`
+		Text string `xml:",chardata"`
+	}
+
+	atomCat struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	}
+
+	AtomHandler struct {
+		getCategoriesFn handlers.GetCategoriesFn
+		getBooksFn      handlers.GetBooksFn
+		searchBooksFn   handlers.SearchBooksFn
+	}
+)
+
+// NewAtomHandler creates a new AtomHandler with the provided functions.
+// This handler serves the OPDS 1.2 catalog.
+func NewAtomHandler(
+	getCategories handlers.GetCategoriesFn,
+	getBooks handlers.GetBooksFn,
+	searchBooks handlers.SearchBooksFn,
+) *AtomHandler {
+	return &AtomHandler{
+		getCategoriesFn: getCategories,
+		getBooksFn:      getBooks,
+		searchBooksFn:   searchBooks,
+	}
+}
+
+// commonLinks are the links shared by every feed of the catalog.
+func commonLinks(self, selfType string) []link {
+	return []link{
+		{Rel: "self", Href: self, Type: selfType},
+		{Rel: "start", Href: basePath, Type: navigationType},
+		{Rel: "search", Href: basePath + "/opensearch.xml", Type: openSearchType},
+	}
+}
+
+// ServeNavigation serves the root navigation feed, with one entry per category.
+func (h *AtomHandler) ServeNavigation(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.getCategoriesFn(r.Context())
+	if err != nil {
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	f := feed{
+		ID:      "urn:bookshelf:root",
+		Title:   catalogTitle,
+		Updated: now,
+		Author:  author{Name: catalogTitle},
+		Links:   commonLinks(basePath, navigationType),
+		Entries: []entry{
+			{
+				ID:      "urn:bookshelf:all",
+				Title:   "All",
+				Updated: now,
+				Content: &content{Type: "text", Text: "Every magazine and book"},
+				Links: []link{
+					{Rel: "subsection", Href: booksPath(basePath+"/books", "", ""), Type: acquisitionType},
+				},
+			},
+		},
+	}
+	for _, c := range categories {
+		f.Entries = append(f.Entries, entry{
+			ID:      categoryURN(c),
+			Title:   c,
+			Updated: now,
+			Links: []link{
+				{Rel: "subsection", Href: booksPath(basePath+"/books", c, ""), Type: acquisitionType},
+			},
+		})
+	}
+
+	writeFeed(w, navigationType, f)
+}
+
+// ServeAcquisition serves the acquisition feed of a category or of a search.
+func (h *AtomHandler) ServeAcquisition(w http.ResponseWriter, r *http.Request) {
+	category, query, books, err := fetchBooks(r, h.getBooksFn, h.searchBooksFn)
+	if err != nil {
+		http.Error(w, "Error fetching books", http.StatusInternalServerError)
+		return
+	}
+
+	title := catalogTitle
+	switch {
+	case query != "":
+		title = "Search results for " + query
+	case category != "":
+		title = category
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	f := feed{
+		ID:      "urn:bookshelf:books:" + r.URL.RawQuery,
+		Title:   title,
+		Updated: now,
+		Author:  author{Name: catalogTitle},
+		Links:   commonLinks(booksPath(basePath+"/books", category, query), acquisitionType),
+		Entries: make([]entry, 0, len(books)),
+	}
+	for _, b := range books {
+		f.Entries = append(f.Entries, bookEntry(b, now))
+	}
+
+	writeFeed(w, acquisitionType, f)
+}
+
+func bookEntry(b entities.Book, updated string) entry {
+	e := entry{
+		ID:         bookURN(b),
+		Title:      b.Title,
+		Updated:    updated,
+		Categories: []atomCat{{Term: b.Category, Label: b.Category}},
+		Links: []link{
+			{Rel: relAcquisition, Href: downloadPath(b), Type: "application/pdf"},
+		},
+	}
+	if b.Description != "" {
+		e.Content = &content{Type: "text", Text: b.Description}
+	}
+	if b.Cover != "" {
+		image, thumbnail := coverPaths(b)
+		e.Links = append(e.Links,
+			link{Rel: relImage, Href: image, Type: "image/jpeg"},
+			link{Rel: relThumbnail, Href: thumbnail, Type: "image/jpeg"},
+		)
+	}
+	return e
+}
+
+func writeFeed(w http.ResponseWriter, contentType string, f feed) {
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		slog.Error("cannot write OPDS feed", slog.Any("error", err))
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(f); err != nil {
+		slog.Error("cannot encode OPDS feed", slog.Any("error", err))
+	}
+}
+
+// openSearchDescription tells the reader apps how to build a search URL.
+const openSearchDescription = `<?xml version="1.0" encoding="UTF-8"?>
+<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">
+  <ShortName>` + catalogTitle + `</ShortName>
+  <Description>Search the Raspberry Pi magazines and books</Description>
+  <InputEncoding>UTF-8</InputEncoding>
+  <OutputEncoding>UTF-8</OutputEncoding>
+  <Url type="` + acquisitionType + `" template="` + basePath + `/books?q={searchTerms}"/>
+</OpenSearchDescription>
+`
+
+func serveOpenSearch(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", openSearchType)
+	if _, err := w.Write([]byte(openSearchDescription)); err != nil {
+		slog.Error("cannot write OpenSearch description", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,173 @@
+package opds
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+)
+
+const opdsJSONType = "application/opds+json"
+
+type (
+	jsonFeed struct {
+		Metadata     jsonMetadata      `json:"metadata"`
+		Links        []jsonLink        `json:"links"`
+		Navigation   []jsonLink        `json:"navigation,omitempty"`
+		Publications []jsonPublication `json:"publications,omitempty"`
+	}
+
+	jsonMetadata struct {
+		Title         string `json:"title"`
+		NumberOfItems *int   `json:"numberOfItems,omitempty"`
+	}
+
+	jsonLink struct {
+		Rel       string `json:"rel,omitempty"`
+		Href      string `json:"href"`
+		Type      string `json:"type,omitempty"`
+		Title     string `json:"title,omitempty"`
+		Templated bool   `json:"templated,omitempty"`
+	}
+
+	jsonPublication struct {
+		Metadata jsonPublicationMetadata `json:"metadata"`
+		Links    []jsonLink              `json:"links"`
+		Images   []jsonLink              `json:"images,omitempty"`
+	}
+
+	jsonPublicationMetadata struct {
+		Type        string        `json:"@type"`
+		Identifier  string        `json:"identifier"`
+		Title       string        `json:"title"`
+		Description string        `json:"description,omitempty"`
+		Subject     []jsonSubject `json:"subject,omitempty"`
+	}
+
+	jsonSubject struct {
+		Name string `json:"name"`
+	}
+
+	JSONHandler struct {
+		getCategoriesFn handlers.GetCategoriesFn
+		getBooksFn      handlers.GetBooksFn
+		searchBooksFn   handlers.SearchBooksFn
+	}
+)
+
+// NewJSONHandler creates a new JSONHandler with the provided functions.
+// This handler serves the OPDS 2.0 catalog.
+func NewJSONHandler(
+	getCategories handlers.GetCategoriesFn,
+	getBooks handlers.GetBooksFn,
+	searchBooks handlers.SearchBooksFn,
+) *JSONHandler {
+	return &JSONHandler{
+		getCategoriesFn: getCategories,
+		getBooksFn:      getBooks,
+		searchBooksFn:   searchBooks,
+	}
+}
+
+// commonJSONLinks are the links shared by every feed of the catalog.
+func commonJSONLinks(self string) []jsonLink {
+	return []jsonLink{
+		{Rel: "self", Href: self, Type: opdsJSONType},
+		{Rel: "start", Href: basePath + "/v2/catalog.json", Type: opdsJSONType},
+		{Rel: "search", Href: basePath + "/v2/books.json{?query}", Type: opdsJSONType, Templated: true},
+	}
+}
+
+// ServeNavigation serves the root navigation feed, with one link per category.
+func (h *JSONHandler) ServeNavigation(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.getCategoriesFn(r.Context())
+	if err != nil {
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
+	f := jsonFeed{
+		Metadata: jsonMetadata{Title: catalogTitle},
+		Links:    commonJSONLinks(basePath + "/v2/catalog.json"),
+		Navigation: []jsonLink{
+			{Href: booksPath(basePath+"/v2/books.json", "", ""), Type: opdsJSONType, Title: "All"},
+		},
+	}
+	for _, c := range categories {
+		f.Navigation = append(f.Navigation, jsonLink{
+			Href:  booksPath(basePath+"/v2/books.json", c, ""),
+			Type:  opdsJSONType,
+			Title: c,
+		})
+	}
+
+	writeJSONFeed(w, f)
+}
+
+// ServeAcquisition serves the publications of a category or of a search.
+// The search term is read from the query parameter of the templated search link.
+func (h *JSONHandler) ServeAcquisition(w http.ResponseWriter, r *http.Request) {
+	if query := r.URL.Query().Get("query"); query != "" {
+		params := r.URL.Query()
+		params.Set("q", query)
+		r.URL.RawQuery = params.Encode()
+	}
+
+	category, query, books, err := fetchBooks(r, h.getBooksFn, h.searchBooksFn)
+	if err != nil {
+		http.Error(w, "Error fetching books", http.StatusInternalServerError)
+		return
+	}
+
+	title := catalogTitle
+	switch {
+	case query != "":
+		title = "Search results for " + query
+	case category != "":
+		title = category
+	}
+
+	count := len(books)
+	f := jsonFeed{
+		Metadata:     jsonMetadata{Title: title, NumberOfItems: &count},
+		Links:        commonJSONLinks(booksPath(basePath+"/v2/books.json", category, query)),
+		Publications: make([]jsonPublication, 0, len(books)),
+	}
+	for _, b := range books {
+		f.Publications = append(f.Publications, publication(b))
+	}
+
+	writeJSONFeed(w, f)
+}
+
+func publication(b entities.Book) jsonPublication {
+	p := jsonPublication{
+		Metadata: jsonPublicationMetadata{
+			Type:        "http://schema.org/Book",
+			Identifier:  bookURN(b),
+			Title:       b.Title,
+			Description: b.Description,
+			Subject:     []jsonSubject{{Name: b.Category}},
+		},
+		Links: []jsonLink{
+			{Rel: relAcquisition, Href: downloadPath(b), Type: "application/pdf"},
+		},
+	}
+	if b.Cover != "" {
+		image, thumbnail := coverPaths(b)
+		p.Images = []jsonLink{
+			{Href: image, Type: "image/jpeg"},
+			{Href: thumbnail, Type: "image/jpeg"},
+		}
+	}
+	return p
+}
+
+func writeJSONFeed(w http.ResponseWriter, f jsonFeed) {
+	w.Header().Set("Content-Type", opdsJSONType)
+	if err := json.NewEncoder(w).Encode(f); err != nil {
+		slog.Error("cannot encode OPDS feed", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,107 @@
+// Package opds publishes the bookshelf as OPDS catalogs, so it can be added
+// to e-reader apps such as KOReader, Moon+ Reader or Thorium.
+//
+// Both OPDS 1.2, based on Atom, and OPDS 2.0, based on JSON, are served.
+// The root of each catalog is a navigation feed listing the categories,
+// every category is an acquisition feed listing its books.
+package opds
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/covers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// basePath is where the router is mounted, feeds link to each other
+	// with absolute paths.
+	basePath = "/opds"
+
+	catalogTitle = "Raspberry Bookshelf"
+)
+
+// NewRouter creates the router serving the OPDS catalogs.
+// It is meant to be mounted under /opds.
+func NewRouter(
+	getCategoriesFn handlers.GetCategoriesFn,
+	getBooksFn handlers.GetBooksFn,
+	searchBooksFn handlers.SearchBooksFn,
+) chi.Router {
+	r := chi.NewRouter()
+
+	atomFeeds := NewAtomHandler(getCategoriesFn, getBooksFn, searchBooksFn)
+	r.Get("/", atomFeeds.ServeNavigation)
+	r.Get("/books", atomFeeds.ServeAcquisition)
+	r.Get("/opensearch.xml", serveOpenSearch)
+
+	jsonFeeds := NewJSONHandler(getCategoriesFn, getBooksFn, searchBooksFn)
+	r.Get("/v2/catalog.json", jsonFeeds.ServeNavigation)
+	r.Get("/v2/books.json", jsonFeeds.ServeAcquisition)
+
+	return r
+}
+
+// booksPath returns the path of an acquisition feed.
+func booksPath(feed, category, query string) string {
+	params := url.Values{}
+	if category != "" {
+		params.Set("cat", category)
+	}
+	if query != "" {
+		params.Set("q", query)
+	}
+	if len(params) == 0 {
+		return feed
+	}
+	return feed + "?" + params.Encode()
+}
+
+func downloadPath(b entities.Book) string {
+	return fmt.Sprintf("/download/%s", url.PathEscape(b.ID))
+}
+
+func bookURN(b entities.Book) string {
+	return "urn:bookshelf:book:" + b.ID
+}
+
+func categoryURN(category string) string {
+	return "urn:bookshelf:category:" + url.QueryEscape(category)
+}
+
+// acquirable filters out the books that cannot be downloaded, every entry of
+// an acquisition feed must have an acquisition link.
+func acquirable(books []entities.Book) []entities.Book {
+	result := make([]entities.Book, 0, len(books))
+	for _, b := range books {
+		if b.Link != "" {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// fetchBooks lists the books of an acquisition feed from its query string.
+func fetchBooks(
+	r *http.Request,
+	getBooksFn handlers.GetBooksFn,
+	searchBooksFn handlers.SearchBooksFn,
+) (category, query string, books []entities.Book, err error) {
+	category = r.URL.Query().Get("cat")
+	query = r.URL.Query().Get("q")
+	var page entities.BookPage
+	if query != "" {
+		page, err = searchBooksFn(r.Context(), query, entities.BookQuery{Category: category})
+	} else {
+		page, err = getBooksFn(r.Context(), entities.BookQuery{Category: category})
+	}
+	return category, query, acquirable(page.Books), err
+}
+
+func coverPaths(b entities.Book) (image, thumbnail string) {
+	return covers.URL(b, covers.SizeLarge), covers.URL(b, covers.SizeThumbnail)
+}
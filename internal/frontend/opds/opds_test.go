@@ -0,0 +1,183 @@
+package opds
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/bookshelf"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T) chi.Router {
+	t.Helper()
+	storage := bookshelf.NewStorage()
+	require.NoError(t, storage.ReplaceAll(t.Context(), []entities.Book{
+		{
+			Title:       "MagPi 1",
+			Description: "First issue",
+			Cover:       "http://localhost/covers/1",
+			Link:        "http://localhost/magpi/1",
+			Category:    "MagPI",
+		},
+		{
+			Title:    "Book 1",
+			Cover:    "http://localhost/covers/book1",
+			Link:     "http://localhost/books/1",
+			Category: "Book",
+		},
+		{
+			Title:    "Book 2",
+			Category: "Book",
+		},
+	}))
+	return NewRouter(storage.GetCategories, storage.Get, storage.Search)
+}
+
+func get(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec
+}
+
+func decodeFeed(t *testing.T, rec *httptest.ResponseRecorder) feed {
+	t.Helper()
+	var f feed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &f))
+	return f
+}
+
+func findLink(links []link, rel string) (link, bool) {
+	for _, l := range links {
+		if l.Rel == rel {
+			return l, true
+		}
+	}
+	return link{}, false
+}
+
+func TestAtomNavigationListsCategories(t *testing.T) {
+	rec := get(t, newTestRouter(t), "/")
+	assert.Equal(t, navigationType, rec.Header().Get("Content-Type"))
+
+	f := decodeFeed(t, rec)
+	titles := make([]string, 0, len(f.Entries))
+	hrefs := make([]string, 0, len(f.Entries))
+	for _, e := range f.Entries {
+		titles = append(titles, e.Title)
+		l, ok := findLink(e.Links, "subsection")
+		require.True(t, ok)
+		assert.Equal(t, acquisitionType, l.Type)
+		hrefs = append(hrefs, l.Href)
+	}
+	assert.Equal(t, []string{"All", "Book", "MagPI"}, titles)
+	assert.Equal(t, []string{"/opds/books", "/opds/books?cat=Book", "/opds/books?cat=MagPI"}, hrefs)
+
+	search, ok := findLink(f.Links, "search")
+	require.True(t, ok)
+	assert.Equal(t, "/opds/opensearch.xml", search.Href)
+}
+
+func TestAtomAcquisitionFeed(t *testing.T) {
+	f := decodeFeed(t, get(t, newTestRouter(t), "/books?cat=Book"))
+
+	// books without a link cannot be acquired
+	require.Len(t, f.Entries, 1)
+	e := f.Entries[0]
+	assert.Equal(t, "Book 1", e.Title)
+
+	acquisition, ok := findLink(e.Links, relAcquisition)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(acquisition.Href, "/download/"))
+	assert.Equal(t, "application/pdf", acquisition.Type)
+
+	image, ok := findLink(e.Links, relImage)
+	require.True(t, ok)
+	assert.Contains(t, image.Href, "/large")
+	thumbnail, ok := findLink(e.Links, relThumbnail)
+	require.True(t, ok)
+	assert.Contains(t, thumbnail.Href, "/thumb")
+}
+
+func TestAtomSearch(t *testing.T) {
+	f := decodeFeed(t, get(t, newTestRouter(t), "/books?q=magpi"))
+	require.Len(t, f.Entries, 1)
+	assert.Equal(t, "MagPi 1", f.Entries[0].Title)
+	require.NotNil(t, f.Entries[0].Content)
+	assert.Equal(t, "First issue", f.Entries[0].Content.Text)
+}
+
+func TestOpenSearchDescription(t *testing.T) {
+	rec := get(t, newTestRouter(t), "/opensearch.xml")
+	assert.Equal(t, openSearchType, rec.Header().Get("Content-Type"))
+
+	var description struct {
+		URLs []struct {
+			Type     string `xml:"type,attr"`
+			Template string `xml:"template,attr"`
+		} `xml:"Url"`
+	}
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &description))
+	require.Len(t, description.URLs, 1)
+	assert.Equal(t, "/opds/books?q={searchTerms}", description.URLs[0].Template)
+	assert.Equal(t, acquisitionType, description.URLs[0].Type)
+}
+
+func TestJSONNavigationListsCategories(t *testing.T) {
+	rec := get(t, newTestRouter(t), "/v2/catalog.json")
+	assert.Equal(t, opdsJSONType, rec.Header().Get("Content-Type"))
+
+	var f jsonFeed
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &f))
+	titles := make([]string, 0, len(f.Navigation))
+	for _, n := range f.Navigation {
+		titles = append(titles, n.Title)
+	}
+	assert.Equal(t, []string{"All", "Book", "MagPI"}, titles)
+
+	var search *jsonLink
+	for _, l := range f.Links {
+		if l.Rel == "search" {
+			search = &l
+		}
+	}
+	require.NotNil(t, search)
+	assert.True(t, search.Templated)
+}
+
+func TestJSONAcquisitionFeed(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		titles []string
+	}{
+		{name: "all", target: "/v2/books.json", titles: []string{"MagPi 1", "Book 1"}},
+		{name: "category", target: "/v2/books.json?cat=MagPI", titles: []string{"MagPi 1"}},
+		{name: "search", target: "/v2/books.json?query=book", titles: []string{"Book 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f jsonFeed
+			require.NoError(t, json.Unmarshal(get(t, newTestRouter(t), tt.target).Body.Bytes(), &f))
+
+			titles := make([]string, 0, len(f.Publications))
+			for _, p := range f.Publications {
+				titles = append(titles, p.Metadata.Title)
+				require.Len(t, p.Links, 1)
+				assert.Equal(t, relAcquisition, p.Links[0].Rel)
+				assert.Len(t, p.Images, 2)
+			}
+			assert.ElementsMatch(t, tt.titles, titles)
+			require.NotNil(t, f.Metadata.NumberOfItems)
+			assert.Equal(t, len(tt.titles), *f.Metadata.NumberOfItems)
+		})
+	}
+}
@@ -3,8 +3,15 @@ package frontend
 import (
 	"embed"
 	"net/http"
+	"time"
 
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/admin"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/api"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/feeds"
 	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/health"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/opds"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/metrics"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -12,23 +19,73 @@ import (
 //go:embed static
 var staticFs embed.FS
 
-// NewHTTPRouter creates a new HTTP router with the provided handler functions.
-func NewHTTPRouter(
-	getCategoriesFn handlers.GetCategoriesFn,
-	getBookFn handlers.GetBookFn,
-	getBooksFn handlers.GetBooksFn,
-) *chi.Mux {
+// Dependencies are the functions the HTTP router serves.
+type Dependencies struct {
+	GetCategories   handlers.GetCategoriesFn
+	GetBook         handlers.GetBookFn
+	ResolveAlias    handlers.ResolveAliasFn
+	GetBooks        handlers.GetBooksFn
+	SearchBooks     handlers.SearchBooksFn
+	LookupMirrored  handlers.LookupMirroredFn
+	LookupCover     handlers.LookupCoverFn
+	GetChanges      feeds.GetChangesFn
+	GetSources      api.GetSourcesFn
+	LookupLocalFile handlers.LookupLocalFileFn
+	// AdminToken protects the admin endpoints, which are only served when
+	// it is set.
+	AdminToken      string
+	Refresh         admin.RefreshFn
+	GetStatus       admin.GetStatusFn
+	GetSnapshotTime health.GetSnapshotTimeFn
+	// StaleAfter is the age after which the readiness probe reports the
+	// catalog as degraded.
+	StaleAfter time.Duration
+	// Registry measures every request, it is served at /metrics.
+	Registry *metrics.Registry
+}
+
+// NewHTTPRouter creates a new HTTP router serving the provided dependencies.
+func NewHTTPRouter(deps Dependencies) *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(metrics.NewHTTPMetrics(deps.Registry).Middleware)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	fileServer := http.FileServer(http.FS(staticFs))
 	r.Handle("/static/*", fileServer)
+	r.Method(http.MethodGet, "/metrics", deps.Registry)
+	r.Get("/healthz", health.NewLivenessHandler().ServeHTTP)
+	r.Get("/readyz", health.NewReadinessHandler(deps.GetStatus, deps.GetSnapshotTime, deps.StaleAfter).ServeHTTP)
+
+	r.Get("/", handlers.NewIndexHandler(deps.GetCategories).ServeHTTP)
+	r.Get("/module/books", handlers.NewBooksHandler(deps.GetBooks, deps.SearchBooks).ServeHTTP)
+	r.Get("/module/books/items", handlers.NewBookItemsHandler(deps.GetBooks, deps.SearchBooks).ServeHTTP)
+	r.Get("/files/{source}/*", handlers.NewLocalFileHandler(deps.LookupLocalFile).ServeHTTP)
+
+	// links to the former IDs of the books are redirected to the current ones
+	r.Group(func(r chi.Router) {
+		r.Use(handlers.RedirectAliases(deps.ResolveAlias, "bookID"))
+
+		r.Get("/module/book/{bookID}", handlers.NewBookHandler(deps.GetBook).ServeHTTP)
+
+		bookPageHandler := handlers.NewBookPageHandler(deps.GetBook, deps.GetCategories)
+		r.Get("/book/{bookID}", bookPageHandler.ServeHTTP)
+		r.Get("/book/{bookID}/{slug}", bookPageHandler.ServeHTTP)
+
+		r.Get("/download/{bookID}", handlers.NewDownloadHandler(deps.GetBook, deps.LookupMirrored).ServeHTTP)
+
+		coverHandler := handlers.NewCoverHandler(deps.GetBook, deps.LookupCover)
+		r.Get("/covers/{bookID}", coverHandler.ServeHTTP)
+		r.Get("/covers/{bookID}/{size}", coverHandler.ServeHTTP)
+	})
 
-	r.Get("/", handlers.NewIndexHandler(getCategoriesFn).ServeHTTP)
-	r.Get("/module/books", handlers.NewBooksHandler(getBooksFn).ServeHTTP)
-	r.Get("/module/book/{bookID}", handlers.NewBookHandler(getBookFn).ServeHTTP)
+	r.Mount("/api/v1", api.NewRouter(deps.GetCategories, deps.GetBook, deps.GetBooks, deps.SearchBooks, deps.GetSources, deps.ResolveAlias))
+	r.Mount("/feeds", feeds.NewRouter(deps.GetChanges))
+	r.Mount("/opds", opds.NewRouter(deps.GetCategories, deps.GetBooks, deps.SearchBooks))
+	if deps.AdminToken != "" {
+		r.Mount("/admin", admin.NewRouter(deps.AdminToken, deps.Refresh, deps.GetStatus))
+	}
 
 	return r
 }
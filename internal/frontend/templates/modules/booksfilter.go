@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"net/url"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+// BooksFilter is what the books module lists: the books of a category,
+// matching a search query or with an availability, and how they are sorted.
+type BooksFilter struct {
+	Category     string
+	Query        string
+	Availability entities.Availability
+	Sort         entities.SortField
+	Order        entities.SortOrder
+}
+
+// WithSort returns the filter sorted by the field in the order.
+func (f BooksFilter) WithSort(field entities.SortField, order entities.SortOrder) BooksFilter {
+	f.Sort = field
+	f.Order = order
+	return f
+}
+
+// SortOrder returns the order the books are sorted in, the default order of
+// the field when the filter does not give one.
+func (f BooksFilter) SortOrder() entities.SortOrder {
+	if f.Order == entities.OrderDefault {
+		return f.Sort.DefaultOrder()
+	}
+	return f.Order
+}
+
+// Reversed returns the opposite of the order the books are sorted in.
+func (f BooksFilter) Reversed() entities.SortOrder {
+	if f.SortOrder() == entities.OrderDesc {
+		return entities.OrderAsc
+	}
+	return entities.OrderDesc
+}
+
+// PageURL returns the URL of the bookshelf page listing the books, from the
+// book after the cursor, or from the first book when the cursor is empty.
+func (f BooksFilter) PageURL(cursor string) string {
+	return withQuery("/", f.values(cursor))
+}
+
+// ModuleURL returns the URL of the books module, from the book after the
+// cursor, or from the first book when the cursor is empty.
+func (f BooksFilter) ModuleURL(cursor string) string {
+	return withQuery("/module/books", f.values(cursor))
+}
+
+// ItemsURL returns the URL of the books after the cursor, appended to the
+// grid while scrolling. group is the year group of the book at the cursor,
+// so the next books only get a heading when they start a new group.
+func (f BooksFilter) ItemsURL(cursor, group string) string {
+	params := f.values(cursor)
+	if group != "" {
+		params.Set("group", group)
+	}
+	return withQuery("/module/books/items", params)
+}
+
+func withQuery(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + params.Encode()
+}
+
+func (f BooksFilter) values(cursor string) url.Values {
+	params := url.Values{}
+	if f.Category != "" {
+		params.Set("cat", f.Category)
+	}
+	if f.Query != "" {
+		params.Set("q", f.Query)
+	}
+	if f.Availability != "" {
+		params.Set("avail", string(f.Availability))
+	}
+	if f.Sort != entities.SortDefault {
+		params.Set("sort", string(f.Sort))
+	}
+	if f.Order != entities.OrderDefault {
+		params.Set("order", string(f.Order))
+	}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	return params
+}
@@ -0,0 +1,17 @@
+package modules
+
+import (
+	"net/url"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+)
+
+// BookURL returns the permalink of the page of a book, such as
+// /book/magpi150/the-magpi-150.
+func BookURL(b *entities.Book) string {
+	path := "/book/" + url.PathEscape(b.ID)
+	if slug := entities.Slug(b.Title); slug != "" {
+		path += "/" + slug
+	}
+	return path
+}
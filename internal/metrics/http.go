@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels the requests that did not match any route, so
+// random paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics measures the HTTP requests served by a chi router.
+type HTTPMetrics struct {
+	requests *Counter
+	duration *Histogram
+}
+
+// NewHTTPMetrics registers the HTTP request metrics in the registry.
+func NewHTTPMetrics(registry *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: registry.NewCounter(
+			"http_requests_total",
+			"Number of HTTP requests by method, route pattern and status code.",
+			"method", "route", "status",
+		),
+		duration: registry.NewHistogram(
+			"http_request_duration_seconds",
+			"Duration of the HTTP requests by method and route pattern.",
+			DefaultBuckets,
+			"method", "route",
+		),
+	}
+}
+
+// Middleware counts and times the requests. The requests are labeled with
+// the chi route pattern, such as /download/{bookID}, rather than the path.
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.Inc(r.Method, route, strconv.Itoa(status))
+		m.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
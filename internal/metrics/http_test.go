@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	registry := NewRegistry()
+	r := chi.NewRouter()
+	r.Use(NewHTTPMetrics(registry).Middleware)
+	r.Get("/download/{bookID}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost/book.pdf", http.StatusFound)
+	})
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/books/{id}", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("{}"))
+		})
+	})
+	r.Handle("/metrics", registry)
+
+	for _, path := range []string{"/download/1", "/download/2", "/api/v1/books/1", "/missing", "/other"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+
+	e := parseExposition(t, w.Body.String())
+	assert.Equal(t, "counter", e.types["http_requests_total"])
+	assert.Equal(t, "histogram", e.types["http_request_duration_seconds"])
+	assert.Equal(t, 2.0, e.samples[`http_requests_total{method="GET",route="/download/{bookID}",status="302"}`],
+		"requests are labeled by route pattern")
+	assert.Equal(t, 1.0, e.samples[`http_requests_total{method="GET",route="/api/v1/books/{id}",status="200"}`],
+		"sub-routers are labeled by the full pattern")
+	assert.Equal(t, 2.0, e.samples[`http_requests_total{method="GET",route="unmatched",status="404"}`])
+	assert.Equal(t, 2.0, e.samples[`http_request_duration_seconds_count{method="GET",route="/download/{bookID}"}`])
+	assert.Equal(t, 2.0, e.samples[`http_request_duration_seconds_bucket{method="GET",route="/download/{bookID}",le="+Inf"}`])
+}
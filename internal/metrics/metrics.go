@@ -0,0 +1,288 @@
+// Package metrics exports the metrics of the application in the Prometheus
+// text exposition format.
+//
+// Counters, gauges and histograms are created from a Registry, with the names
+// of their labels. The values of the labels are given when a metric is
+// updated, each combination of values is a separate series.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets, in seconds,
+// suited to the duration of HTTP requests.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics and writes them when scraped.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+	hooks    []func(ctx context.Context)
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// NewCounter registers a counter, a value that only goes up.
+// It panics if the name is already registered.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{family: r.register(name, help, "counter", labels, nil)}
+}
+
+// NewGauge registers a gauge, a value that can go up and down.
+// It panics if the name is already registered.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{family: r.register(name, help, "gauge", labels, nil)}
+}
+
+// NewHistogram registers a histogram counting the observations in buckets
+// with the given upper bounds. It panics if the name is already registered.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Histogram{family: r.register(name, help, "histogram", labels, buckets)}
+}
+
+// OnScrape registers a function called before the metrics are written, to
+// update the gauges that mirror the state of another component.
+func (r *Registry) OnScrape(fn func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, fn)
+}
+
+// WriteTo runs the scrape functions and writes every metric in the text
+// exposition format, sorted by name.
+func (r *Registry) WriteTo(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	hooks := slices.Clone(r.hooks)
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+
+	slices.SortFunc(families, func(a, b *family) int {
+		return strings.Compare(a.name, b.name)
+	})
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP writes the metrics, it is the handler scraped by Prometheus.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.WriteTo(req.Context(), w); err != nil {
+		slog.ErrorContext(req.Context(), "cannot write the metrics", slog.Any("error", err))
+	}
+}
+
+func (r *Registry) register(name, help, kind string, labels []string, buckets []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.families[name]; ok {
+		panic(fmt.Sprintf("metrics: %s is already registered", name))
+	}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*series{},
+	}
+	r.families[name] = f
+	return f
+}
+
+// Counter is a value that only goes up, such as a number of requests.
+type Counter struct {
+	family *family
+}
+
+// Inc adds one to the series of the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the series of the given label values.
+// It panics if v is negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: %s cannot decrease", c.family.name))
+	}
+	c.family.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Gauge is a value that can go up and down, such as a number of books.
+type Gauge struct {
+	family *family
+}
+
+// Set sets the series of the given label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.family.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Reset removes every series, so label values that are gone are not
+// exported anymore.
+func (g *Gauge) Reset() {
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	clear(g.family.series)
+}
+
+// Histogram counts observations, such as durations, in buckets.
+type Histogram struct {
+	family *family
+}
+
+// Observe adds v to the series of the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.family.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.family.buckets))
+		}
+		for i, bound := range h.family.buckets {
+			if v <= bound {
+				s.counts[i]++
+			}
+		}
+		s.count++
+		s.sum += v
+	})
+}
+
+// family is a metric with all its series.
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is the value of a metric for a combination of label values.
+type series struct {
+	// labels are the formatted label pairs, without braces.
+	labels string
+	value  float64
+	// counts are the cumulative counts of the histogram buckets.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (f *family) update(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	pairs := make([]string, len(f.labels))
+	for i, name := range f.labels {
+		pairs[i] = labelPair(name, labelValues[i])
+	}
+	key := strings.Join(pairs, ",")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			writeSample(w, f.name, s.labels, s.value)
+			continue
+		}
+		for i, bound := range f.buckets {
+			var count uint64
+			if s.counts != nil {
+				count = s.counts[i]
+			}
+			writeSample(w, f.name+"_bucket", joinLabels(s.labels, labelPair("le", formatFloat(bound))), float64(count))
+		}
+		writeSample(w, f.name+"_bucket", joinLabels(s.labels, labelPair("le", "+Inf")), float64(s.count))
+		writeSample(w, f.name+"_sum", s.labels, s.sum)
+		writeSample(w, f.name+"_count", s.labels, float64(s.count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func joinLabels(labels, pair string) string {
+	if labels == "" {
+		return pair
+	}
+	return labels + "," + pair
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func labelPair(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
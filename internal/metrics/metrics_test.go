@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exposition is a parsed text exposition.
+type exposition struct {
+	help    map[string]string
+	types   map[string]string
+	samples map[string]float64
+	// order lists the samples as written.
+	order []string
+}
+
+// parseExposition parses the text exposition format, samples are keyed by
+// their name and labels as written, such as requests_total{code="200"}.
+func parseExposition(t *testing.T, text string) exposition {
+	t.Helper()
+	e := exposition{
+		help:    map[string]string{},
+		types:   map[string]string{},
+		samples: map[string]float64{},
+	}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "# HELP "):
+			name, help, ok := strings.Cut(strings.TrimPrefix(line, "# HELP "), " ")
+			require.True(t, ok, "malformed HELP line %q", line)
+			e.help[name] = help
+		case strings.HasPrefix(line, "# TYPE "):
+			name, kind, ok := strings.Cut(strings.TrimPrefix(line, "# TYPE "), " ")
+			require.True(t, ok, "malformed TYPE line %q", line)
+			require.Contains(t, []string{"counter", "gauge", "histogram"}, kind)
+			e.types[name] = kind
+		default:
+			i := strings.LastIndexByte(line, ' ')
+			require.Positive(t, i, "malformed sample %q", line)
+			value, err := strconv.ParseFloat(line[i+1:], 64)
+			require.NoError(t, err, "malformed value in %q", line)
+			key := line[:i]
+			require.NotContains(t, e.samples, key, "duplicated sample")
+			e.samples[key] = value
+			e.order = append(e.order, key)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return e
+}
+
+func scrape(t *testing.T, r *Registry) exposition {
+	t.Helper()
+	var sb strings.Builder
+	require.NoError(t, r.WriteTo(t.Context(), &sb))
+	return parseExposition(t, sb.String())
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("downloads_total", "Number of downloads.", "served")
+	c.Inc("mirror")
+	c.Inc("mirror")
+	c.Add(3, "upstream")
+
+	e := scrape(t, r)
+	assert.Equal(t, "counter", e.types["downloads_total"])
+	assert.Equal(t, "Number of downloads.", e.help["downloads_total"])
+	assert.Equal(t, map[string]float64{
+		`downloads_total{served="mirror"}`:   2,
+		`downloads_total{served="upstream"}`: 3,
+	}, e.samples)
+
+	assert.Panics(t, func() { c.Add(-1, "mirror") }, "counters cannot decrease")
+	assert.Panics(t, func() { c.Inc() }, "every label needs a value")
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("books", "Number of books.", "category")
+	g.Set(10, "MagPI")
+	g.Set(2, "Book")
+	g.Set(12, "MagPI")
+
+	e := scrape(t, r)
+	assert.Equal(t, "gauge", e.types["books"])
+	assert.Equal(t, []string{`books{category="Book"}`, `books{category="MagPI"}`}, e.order,
+		"series are sorted")
+	assert.Equal(t, 12.0, e.samples[`books{category="MagPI"}`])
+
+	g.Reset()
+	g.Set(1, "Book")
+	e = scrape(t, r)
+	assert.Equal(t, map[string]float64{`books{category="Book"}`: 1}, e.samples)
+}
+
+func TestGaugeWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("last_success_timestamp_seconds", "Time of the last success.")
+
+	e := scrape(t, r)
+	assert.Empty(t, e.samples, "nothing is written before the first value")
+
+	g.Set(1735689600)
+	e = scrape(t, r)
+	assert.Equal(t, map[string]float64{"last_success_timestamp_seconds": 1735689600}, e.samples)
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("fetch_duration_seconds", "Duration of the fetches.", []float64{1, 0.1}, "source")
+	h.Observe(0.05, "magpi")
+	h.Observe(0.5, "magpi")
+	h.Observe(5, "magpi")
+
+	e := scrape(t, r)
+	assert.Equal(t, "histogram", e.types["fetch_duration_seconds"])
+	assert.Equal(t, []string{
+		`fetch_duration_seconds_bucket{source="magpi",le="0.1"}`,
+		`fetch_duration_seconds_bucket{source="magpi",le="1"}`,
+		`fetch_duration_seconds_bucket{source="magpi",le="+Inf"}`,
+		`fetch_duration_seconds_sum{source="magpi"}`,
+		`fetch_duration_seconds_count{source="magpi"}`,
+	}, e.order, "buckets are sorted and cumulative")
+	assert.Equal(t, 1.0, e.samples[`fetch_duration_seconds_bucket{source="magpi",le="0.1"}`])
+	assert.Equal(t, 2.0, e.samples[`fetch_duration_seconds_bucket{source="magpi",le="1"}`])
+	assert.Equal(t, 3.0, e.samples[`fetch_duration_seconds_bucket{source="magpi",le="+Inf"}`])
+	assert.InDelta(t, 5.55, e.samples[`fetch_duration_seconds_sum{source="magpi"}`], 1e-9)
+	assert.Equal(t, 3.0, e.samples[`fetch_duration_seconds_count{source="magpi"}`])
+}
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("books", "Number of books.\nPer category.", "category").Set(1, "Say \"hi\"\\\n")
+
+	var sb strings.Builder
+	require.NoError(t, r.WriteTo(t.Context(), &sb))
+	assert.Equal(t, "# HELP books Number of books.\\nPer category.\n"+
+		"# TYPE books gauge\n"+
+		`books{category="Say \"hi\"\\\n"} 1`+"\n", sb.String())
+}
+
+func TestOnScrape(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("mirror_usage_bytes", "Size of the mirror.")
+	usage := 0.0
+	r.OnScrape(func(context.Context) {
+		usage += 1024
+		g.Set(usage)
+	})
+
+	assert.Equal(t, 1024.0, scrape(t, r).samples["mirror_usage_bytes"])
+	assert.Equal(t, 2048.0, scrape(t, r).samples["mirror_usage_bytes"], "the gauge is updated on every scrape")
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "Number of requests.")
+	assert.Panics(t, func() { r.NewGauge("requests_total", "Number of requests.") })
+}
@@ -0,0 +1,451 @@
+// Package mirror keeps a local copy of the PDFs in the catalog, so they can
+// be served without going through the upstream site.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrQuotaExceeded is returned when a download does not fit in the quota.
+var ErrQuotaExceeded = errors.New("mirror quota exceeded")
+
+// BookSource provides the books that should be mirrored.
+type BookSource interface {
+	Get(ctx context.Context, q entities.BookQuery) (entities.BookPage, error)
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Dir is the directory where the files are stored.
+	Dir string
+	// Concurrency is the maximum number of simultaneous downloads.
+	Concurrency int
+	// Quota is the maximum number of bytes used by the files, zero is unlimited.
+	Quota int64
+	// Interval is the time between two synchronizations with the catalog.
+	Interval time.Duration
+	// HTTPClient is used for the downloads, a client with timeouts suited
+	// to large files if nil.
+	HTTPClient *http.Client
+}
+
+// newHTTPClient returns the default client of the downloads. Connecting and
+// waiting for the response headers are bounded by short timeouts, while a
+// whole PDF may take a while to download on a slow link.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	return &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Minute,
+	}
+}
+
+// Manager downloads the PDF of every available book into a local store
+// and keeps track of what was mirrored.
+type Manager struct {
+	books   BookSource
+	options Options
+	store   store
+
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// NewManager creates a new instance of Manager.
+// It reads the index of the files mirrored by a previous run.
+func NewManager(books BookSource, options Options) (*Manager, error) {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = newHTTPClient()
+	}
+
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mirror directory: %w", err)
+	}
+
+	s := store{dir: options.Dir}
+	entries, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		books:   books,
+		options: options,
+		store:   s,
+		entries: entries,
+	}, nil
+}
+
+// Run periodically synchronizes the mirror with the catalog until the
+// provided context is done.
+// This operation is blocking, you might want to run it in a separate goroutine.
+func (m *Manager) Run(ctx context.Context) error {
+	slog.Debug("starting the mirror", slog.String("dir", m.options.Dir))
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("context is done, exiting the mirror")
+			return nil
+		case <-timer.C:
+			if err := m.Sync(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to synchronize the mirror", slog.Any("error", err))
+			}
+			timer.Reset(m.options.Interval)
+		}
+	}
+}
+
+// Lookup returns the path of the local copy of the book PDF, if it was mirrored.
+func (m *Manager) Lookup(_ context.Context, bookID string) (string, bool) {
+	m.mu.RLock()
+	entry, ok := m.entries[bookID]
+	m.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return m.store.objectPath(entry.Hash), true
+}
+
+// Usage returns the number of bytes used by the mirrored files.
+func (m *Manager) Usage() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.usage()
+}
+
+// usage must be called with the lock held.
+func (m *Manager) usage() int64 {
+	var total int64
+	seen := map[string]bool{}
+	for _, e := range m.entries {
+		if !seen[e.Hash] {
+			seen[e.Hash] = true
+			total += e.Size
+		}
+	}
+	return total
+}
+
+// Sync downloads the PDFs of the books that are not mirrored yet, or whose
+// link changed, and removes the files of the books that left the catalog.
+func (m *Manager) Sync(ctx context.Context) error {
+	catalog, err := m.books.Get(ctx, entities.BookQuery{})
+	if err != nil {
+		return fmt.Errorf("cannot get books: %w", err)
+	}
+	books := catalog.Books
+
+	wanted := map[string]entities.Book{}
+	for _, b := range books {
+		// files of local sources are already served by the application
+		if entities.IsRemote(b.Link) {
+			wanted[b.ID] = b
+		}
+	}
+
+	if err := m.prune(wanted); err != nil {
+		slog.ErrorContext(ctx, "failed to prune the mirror", slog.Any("error", err))
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(m.options.Concurrency)
+	for _, b := range books {
+		if _, ok := wanted[b.ID]; !ok || m.isMirrored(b) {
+			continue
+		}
+		g.Go(func() error {
+			err := m.download(ctx, b)
+			switch {
+			case errors.Is(err, ErrQuotaExceeded):
+				slog.WarnContext(ctx, "skipping download, quota exceeded", slog.String("bookID", b.ID))
+			case err != nil && ctx.Err() == nil:
+				slog.ErrorContext(ctx, "failed to mirror book",
+					slog.String("bookID", b.ID),
+					slog.String("link", b.Link),
+					slog.Any("error", err),
+				)
+			}
+			// one failed download must not cancel the others
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func (m *Manager) isMirrored(b entities.Book) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[b.ID]
+	return ok && entry.Source == b.Link
+}
+
+// prune forgets the books that are no longer wanted and deletes the files
+// that are not referenced anymore. A book that changed ID but not link keeps
+// its file.
+func (m *Manager) prune(wanted map[string]entities.Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byLink := make(map[string]string, len(wanted))
+	for id, b := range wanted {
+		byLink[b.Link] = id
+	}
+
+	removed := map[string]bool{}
+	renamed := false
+	for id, entry := range m.entries {
+		if _, ok := wanted[id]; ok {
+			continue
+		}
+		if newID, ok := byLink[entry.Source]; ok {
+			if _, mirrored := m.entries[newID]; !mirrored {
+				m.entries[newID] = entry
+				delete(m.entries, id)
+				renamed = true
+			}
+		}
+	}
+	for id, entry := range m.entries {
+		if b, ok := wanted[id]; !ok || b.Link != entry.Source {
+			delete(m.entries, id)
+			removed[entry.Hash] = true
+		}
+	}
+	if len(removed) == 0 {
+		if renamed {
+			return m.store.saveIndex(m.entries)
+		}
+		return nil
+	}
+
+	for _, e := range m.entries {
+		delete(removed, e.Hash)
+	}
+	var errs []error
+	for hash := range removed {
+		errs = append(errs, m.store.removeObject(hash))
+	}
+	errs = append(errs, m.store.saveIndex(m.entries))
+	return errors.Join(errs...)
+}
+
+// download fetches the PDF of the book, resuming a previous partial download
+// when the file did not change since, and records it in the index.
+func (m *Manager) download(ctx context.Context, b entities.Book) error {
+	partial := m.store.partialPath(b.Link)
+	if err := os.MkdirAll(filepath.Dir(partial), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			slog.Error("Cannot close partial file", slog.Any("error", err))
+		}
+	}()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	validator, err := m.store.loadValidator(b.Link)
+	if err != nil {
+		return err
+	}
+	if offset > 0 && validator == "" {
+		// the partial file could be the beginning of another version of the file
+		if offset, err = truncate(f); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.Link, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// the server sends the whole file when it changed since
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := m.options.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("Cannot close response body", slog.Any("error", err))
+		}
+	}()
+
+	contentRange := resp.Header.Get("Content-Range")
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, _, ok := parseContentRange(contentRange); !ok || start != offset {
+			_ = f.Close()
+			return errors.Join(
+				fmt.Errorf("unexpected content range %q for offset %d", contentRange, offset),
+				m.store.removePartial(b.Link),
+			)
+		}
+		slog.DebugContext(ctx, "resuming download", slog.String("bookID", b.ID), slog.Int64("offset", offset))
+	case http.StatusOK:
+		// the server ignored the range or the file changed, start over
+		if offset, err = truncate(f); err != nil {
+			return err
+		}
+		if err := m.store.saveValidator(b.Link, validatorOf(resp.Header)); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file already holds the whole content, when it has its size
+		if _, total, ok := parseContentRange(contentRange); !ok || total != offset {
+			_ = f.Close()
+			return errors.Join(
+				fmt.Errorf("partial download of %d bytes does not match content range %q", offset, contentRange),
+				m.store.removePartial(b.Link),
+			)
+		}
+	default:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		if resp.ContentLength > 0 && !m.fits(offset+resp.ContentLength) {
+			_ = f.Close()
+			_ = m.store.removePartial(b.Link)
+			return ErrQuotaExceeded
+		}
+		if _, err := io.Copy(f, resp.Body); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	hash, size, err := m.store.commit(b.Link)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.fitsLocked(hash, size) {
+		if !m.isReferenced(hash) {
+			_ = m.store.removeObject(hash)
+		}
+		return ErrQuotaExceeded
+	}
+
+	m.entries[b.ID] = Entry{
+		Hash:       hash,
+		Size:       size,
+		Source:     b.Link,
+		MirroredAt: time.Now().UTC(),
+	}
+	slog.InfoContext(ctx, "mirrored book", slog.String("bookID", b.ID), slog.Int64("size", size))
+	return m.store.saveIndex(m.entries)
+}
+
+// truncate empties the partial file and returns the new offset.
+func truncate(f *os.File) (int64, error) {
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	return f.Seek(0, io.SeekStart)
+}
+
+// validatorOf returns the validator of the response to send in If-Range:
+// its ETag when it is a strong one, or else its Last-Modified date.
+func validatorOf(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// parseContentRange parses a "bytes first-last/total" or "bytes */total"
+// Content-Range header. The first byte is -1 when the range is "*", and the
+// total is -1 when it is unknown.
+func parseContentRange(header string) (first, total int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	first, total = -1, -1
+	var err error
+	if rng != "*" {
+		start, _, found := strings.Cut(rng, "-")
+		if !found {
+			return 0, 0, false
+		}
+		if first, err = strconv.ParseInt(start, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return first, total, true
+}
+
+// fits checks if a new file of the given size fits in the quota.
+func (m *Manager) fits(size int64) bool {
+	if m.options.Quota <= 0 {
+		return true
+	}
+	return m.Usage()+size <= m.options.Quota
+}
+
+// fitsLocked checks if the committed file fits in the quota.
+// It must be called with the lock held.
+func (m *Manager) fitsLocked(hash string, size int64) bool {
+	if m.options.Quota <= 0 || m.isReferenced(hash) {
+		return true
+	}
+	return m.usage()+size <= m.options.Quota
+}
+
+// isReferenced must be called with the lock held.
+func (m *Manager) isReferenced(hash string) bool {
+	for _, e := range m.entries {
+		if e.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
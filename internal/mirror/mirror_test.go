@@ -0,0 +1,313 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticBooks []entities.Book
+
+func (s staticBooks) Get(_ context.Context, _ entities.BookQuery) (entities.BookPage, error) {
+	return entities.BookPage{Books: s, Total: len(s)}, nil
+}
+
+// pdfServer serves files with Range support and records what was requested.
+type pdfServer struct {
+	*httptest.Server
+	files map[string][]byte
+
+	mu       sync.Mutex
+	ranges   map[string]string
+	ifRanges map[string]string
+	inFlight atomic.Int64
+	maxSeen  atomic.Int64
+	delay    time.Duration
+}
+
+func newPDFServer(t *testing.T, files map[string][]byte) *pdfServer {
+	s := &pdfServer{files: files, ranges: map[string]string{}, ifRanges: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for {
+			seen := s.maxSeen.Load()
+			if current <= seen || s.maxSeen.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(s.delay)
+
+		s.mu.Lock()
+		s.ranges[r.URL.Path] = r.Header.Get("Range")
+		s.ifRanges[r.URL.Path] = r.Header.Get("If-Range")
+		s.mu.Unlock()
+
+		content, ok := s.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag(content))
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *pdfServer) rangeFor(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ranges[path]
+}
+
+func (s *pdfServer) ifRangeFor(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ifRanges[path]
+}
+
+func etag(content []byte) string {
+	return fmt.Sprintf(`"%x"`, sha256.Sum256(content))
+}
+
+// writePartial leaves a partial download of the source, as an interrupted
+// download of the file with the validator would.
+func writePartial(t *testing.T, m *Manager, source string, content []byte, validator string) string {
+	t.Helper()
+	partial := m.store.partialPath(source)
+	require.NoError(t, os.MkdirAll(filepath.Dir(partial), 0o755))
+	require.NoError(t, os.WriteFile(partial, content, 0o644))
+	require.NoError(t, m.store.saveValidator(source, validator))
+	return partial
+}
+
+func newTestManager(t *testing.T, books []entities.Book, options Options) *Manager {
+	t.Helper()
+	if options.Dir == "" {
+		options.Dir = t.TempDir()
+	}
+	m, err := NewManager(staticBooks(books), options)
+	require.NoError(t, err)
+	return m
+}
+
+func readMirrored(t *testing.T, m *Manager, bookID string) []byte {
+	t.Helper()
+	path, ok := m.Lookup(t.Context(), bookID)
+	require.True(t, ok, "book %s should be mirrored", bookID)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return content
+}
+
+func TestSyncMirrorsAvailableBooks(t *testing.T) {
+	server := newPDFServer(t, map[string][]byte{
+		"/1.pdf": []byte("magpi issue 1"),
+		"/2.pdf": []byte("magpi issue 2"),
+	})
+	books := []entities.Book{
+		{ID: "1", Link: server.URL + "/1.pdf"},
+		{ID: "2", Link: server.URL + "/2.pdf"},
+		{ID: "locked"},
+		{ID: "local", Link: "/files/nas/Training/Intro.pdf"},
+	}
+
+	dir := t.TempDir()
+	m := newTestManager(t, books, Options{Dir: dir})
+	require.NoError(t, m.Sync(t.Context()))
+
+	assert.Equal(t, []byte("magpi issue 1"), readMirrored(t, m, "1"))
+	assert.Equal(t, []byte("magpi issue 2"), readMirrored(t, m, "2"))
+	_, ok := m.Lookup(t.Context(), "locked")
+	assert.False(t, ok)
+	_, ok = m.Lookup(t.Context(), "local")
+	assert.False(t, ok, "files of local sources are not mirrored")
+	assert.Equal(t, int64(26), m.Usage())
+
+	// a new manager picks up the index of the previous one
+	restarted := newTestManager(t, books, Options{Dir: dir})
+	assert.Equal(t, []byte("magpi issue 1"), readMirrored(t, restarted, "1"))
+}
+
+func TestSyncDeduplicatesContent(t *testing.T) {
+	server := newPDFServer(t, map[string][]byte{
+		"/a.pdf": []byte("same content"),
+		"/b.pdf": []byte("same content"),
+	})
+	books := []entities.Book{
+		{ID: "a", Link: server.URL + "/a.pdf"},
+		{ID: "b", Link: server.URL + "/b.pdf"},
+	}
+
+	m := newTestManager(t, books, Options{})
+	require.NoError(t, m.Sync(t.Context()))
+
+	pathA, _ := m.Lookup(t.Context(), "a")
+	pathB, _ := m.Lookup(t.Context(), "b")
+	assert.Equal(t, pathA, pathB)
+	assert.Equal(t, int64(len("same content")), m.Usage())
+}
+
+func TestSyncResumesPartialDownload(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	server := newPDFServer(t, map[string][]byte{"/big.pdf": content})
+	books := []entities.Book{{ID: "big", Link: server.URL + "/big.pdf"}}
+
+	m := newTestManager(t, books, Options{})
+	partial := writePartial(t, m, books[0].Link, content[:400], etag(content))
+
+	require.NoError(t, m.Sync(t.Context()))
+
+	assert.Equal(t, "bytes=400-", server.rangeFor("/big.pdf"))
+	assert.Equal(t, etag(content), server.ifRangeFor("/big.pdf"))
+	assert.Equal(t, content, readMirrored(t, m, "big"))
+	assert.NoFileExists(t, partial)
+	assert.NoFileExists(t, m.store.validatorPath(books[0].Link))
+}
+
+func TestSyncRestartsDownloadOfChangedFile(t *testing.T) {
+	old := []byte(strings.Repeat("old content ", 100))
+	content := []byte(strings.Repeat("0123456789", 100))
+	server := newPDFServer(t, map[string][]byte{"/big.pdf": content})
+	books := []entities.Book{{ID: "big", Link: server.URL + "/big.pdf"}}
+
+	tests := []struct {
+		name      string
+		validator string
+		wantRange string
+	}{
+		{name: "the file was replaced upstream", validator: etag(old), wantRange: "bytes=400-"},
+		{name: "the partial file has no validator", wantRange: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, books, Options{})
+			writePartial(t, m, books[0].Link, old[:400], tt.validator)
+
+			require.NoError(t, m.Sync(t.Context()))
+
+			assert.Equal(t, tt.wantRange, server.rangeFor("/big.pdf"))
+			assert.Equal(t, content, readMirrored(t, m, "big"), "the old content is not spliced with the new one")
+		})
+	}
+}
+
+func TestSyncChecksSizeOfCompletePartialDownload(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	server := newPDFServer(t, map[string][]byte{"/big.pdf": content})
+	books := []entities.Book{{ID: "big", Link: server.URL + "/big.pdf"}}
+
+	m := newTestManager(t, books, Options{})
+	writePartial(t, m, books[0].Link, content, etag(content))
+	require.NoError(t, m.Sync(t.Context()))
+	assert.Equal(t, content, readMirrored(t, m, "big"), "the partial file already held the whole content")
+
+	m = newTestManager(t, books, Options{})
+	partial := writePartial(t, m, books[0].Link, append(bytes.Clone(content), "garbage"...), etag(content))
+	require.NoError(t, m.Sync(t.Context()))
+	_, ok := m.Lookup(t.Context(), "big")
+	assert.False(t, ok, "a partial file larger than the file is not mirrored")
+	assert.NoFileExists(t, partial, "the next download starts over")
+
+	require.NoError(t, m.Sync(t.Context()))
+	assert.Equal(t, content, readMirrored(t, m, "big"))
+}
+
+func TestSyncRespectsQuota(t *testing.T) {
+	server := newPDFServer(t, map[string][]byte{
+		"/small.pdf": []byte("12345"),
+		"/large.pdf": []byte("1234567890"),
+	})
+	books := []entities.Book{
+		{ID: "small", Link: server.URL + "/small.pdf"},
+		{ID: "large", Link: server.URL + "/large.pdf"},
+	}
+
+	m := newTestManager(t, books, Options{Quota: 12})
+	require.NoError(t, m.Sync(t.Context()))
+
+	assert.Equal(t, []byte("12345"), readMirrored(t, m, "small"))
+	_, ok := m.Lookup(t.Context(), "large")
+	assert.False(t, ok, "the large file does not fit in the quota")
+	assert.LessOrEqual(t, m.Usage(), int64(12))
+}
+
+func TestSyncBoundsConcurrency(t *testing.T) {
+	files := map[string][]byte{}
+	books := []entities.Book{}
+	server := newPDFServer(t, files)
+	server.delay = 20 * time.Millisecond
+	for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+		files["/"+id+".pdf"] = []byte("content " + id)
+		books = append(books, entities.Book{ID: id, Link: server.URL + "/" + id + ".pdf"})
+	}
+
+	m := newTestManager(t, books, Options{Concurrency: 2})
+	require.NoError(t, m.Sync(t.Context()))
+
+	assert.Equal(t, int64(2), server.maxSeen.Load())
+	for _, b := range books {
+		_, ok := m.Lookup(t.Context(), b.ID)
+		assert.True(t, ok)
+	}
+}
+
+func TestSyncRemovesBooksThatLeftTheCatalog(t *testing.T) {
+	server := newPDFServer(t, map[string][]byte{
+		"/1.pdf":  []byte("issue 1"),
+		"/1b.pdf": []byte("issue 1, new edition"),
+	})
+
+	dir := t.TempDir()
+	m := newTestManager(t, []entities.Book{{ID: "1", Link: server.URL + "/1.pdf"}}, Options{Dir: dir})
+	require.NoError(t, m.Sync(t.Context()))
+	oldPath, _ := m.Lookup(t.Context(), "1")
+
+	// the link of the book changed, the file is downloaded again
+	m.books = staticBooks{{ID: "1", Link: server.URL + "/1b.pdf"}}
+	require.NoError(t, m.Sync(t.Context()))
+	assert.Equal(t, []byte("issue 1, new edition"), readMirrored(t, m, "1"))
+	assert.NoFileExists(t, oldPath)
+
+	m.books = staticBooks{}
+	require.NoError(t, m.Sync(t.Context()))
+	_, ok := m.Lookup(t.Context(), "1")
+	assert.False(t, ok)
+	assert.Zero(t, m.Usage())
+}
+
+func TestSyncKeepsFileOfRenamedBook(t *testing.T) {
+	server := newPDFServer(t, map[string][]byte{"/1.pdf": []byte("issue 1")})
+
+	dir := t.TempDir()
+	m := newTestManager(t, []entities.Book{{ID: "old", Link: server.URL + "/1.pdf"}}, Options{Dir: dir})
+	require.NoError(t, m.Sync(t.Context()))
+	oldPath, _ := m.Lookup(t.Context(), "old")
+
+	server.Close()
+	m.books = staticBooks{{ID: "magpi1", Link: server.URL + "/1.pdf"}}
+	require.NoError(t, m.Sync(t.Context()))
+	path, ok := m.Lookup(t.Context(), "magpi1")
+	require.True(t, ok, "the file follows the new ID without being downloaded again")
+	assert.Equal(t, oldPath, path)
+	_, ok = m.Lookup(t.Context(), "old")
+	assert.False(t, ok)
+
+	restarted := newTestManager(t, nil, Options{Dir: dir})
+	assert.Equal(t, []byte("issue 1"), readMirrored(t, restarted, "magpi1"))
+}
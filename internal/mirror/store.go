@@ -0,0 +1,181 @@
+package mirror
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/atomicfile"
+)
+
+const (
+	indexFileName = "index.json"
+	// indexFileVersion is the current version of the index file format.
+	indexFileVersion = 1
+)
+
+// Entry describes a mirrored PDF.
+type Entry struct {
+	// Hash is the SHA-256 of the file content, it is also its name in the store.
+	Hash string `json:"hash"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+	// Source is the URL the file was downloaded from.
+	Source string `json:"source"`
+	// MirroredAt is the time the download completed.
+	MirroredAt time.Time `json:"mirroredAt"`
+}
+
+type indexFile struct {
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// store is a content-addressed file store.
+// Complete files live in objects/, named by the SHA-256 of their content,
+// so books sharing the same PDF only use the disk space once.
+// Downloads in progress live in partial/, named by the hash of their source
+// URL, so an interrupted download can be resumed. Next to each of them, a
+// .validator file holds the ETag or Last-Modified of the downloaded file, so
+// the download is only resumed when the file did not change.
+type store struct {
+	dir string
+}
+
+func (s store) objectPath(hash string) string {
+	return filepath.Join(s.dir, "objects", hash[:2], hash+".pdf")
+}
+
+func (s store) partialPath(source string) string {
+	return s.partialName(source) + ".part"
+}
+
+func (s store) validatorPath(source string) string {
+	return s.partialName(source) + ".validator"
+}
+
+func (s store) partialName(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(s.dir, "partial", hex.EncodeToString(sum[:16]))
+}
+
+func (s store) indexPath() string {
+	return filepath.Join(s.dir, indexFileName)
+}
+
+// commit moves the complete partial file of the source into the objects
+// directory and returns its hash and size.
+func (s store) commit(source string) (string, int64, error) {
+	partial := s.partialPath(source)
+	f, err := os.Open(partial)
+	if err != nil {
+		return "", 0, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	_ = f.Close()
+	if err != nil {
+		return "", 0, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	if err := removeFile(s.validatorPath(source)); err != nil {
+		return "", 0, err
+	}
+
+	object := s.objectPath(sum)
+	if err := os.MkdirAll(filepath.Dir(object), 0o755); err != nil {
+		return "", 0, err
+	}
+	if _, err := os.Stat(object); err == nil {
+		// the same content was already mirrored for another book
+		return sum, size, os.Remove(partial)
+	}
+	return sum, size, os.Rename(partial, object)
+}
+
+// removeObject deletes a file from the objects directory.
+func (s store) removeObject(hash string) error {
+	return removeFile(s.objectPath(hash))
+}
+
+// removePartial deletes the partial file of the source and its validator,
+// the next download starts over.
+func (s store) removePartial(source string) error {
+	return errors.Join(
+		removeFile(s.partialPath(source)),
+		removeFile(s.validatorPath(source)),
+	)
+}
+
+// loadValidator returns the validator of the partial file of the source,
+// empty when it is unknown.
+func (s store) loadValidator(source string) (string, error) {
+	data, err := os.ReadFile(s.validatorPath(source))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// saveValidator records the validator of the partial file of the source,
+// an empty validator is removed.
+func (s store) saveValidator(source, validator string) error {
+	if validator == "" {
+		return removeFile(s.validatorPath(source))
+	}
+	return os.WriteFile(s.validatorPath(source), []byte(validator), 0o644)
+}
+
+// removeFile deletes a file, a missing file is not an error.
+func removeFile(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// loadIndex reads the index file, a missing file is an empty index.
+func (s store) loadIndex() (map[string]Entry, error) {
+	data, err := os.ReadFile(s.indexPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot decode mirror index: %w", err)
+	}
+	if file.Version < 1 || file.Version > indexFileVersion {
+		return nil, fmt.Errorf("unsupported mirror index version %d", file.Version)
+	}
+	if file.Entries == nil {
+		file.Entries = map[string]Entry{}
+	}
+	return file.Entries, nil
+}
+
+// saveIndex atomically writes the index file.
+func (s store) saveIndex(entries map[string]Entry) error {
+	data, err := json.MarshalIndent(indexFile{
+		Version: indexFileVersion,
+		Entries: entries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(s.indexPath(), data)
+}
@@ -0,0 +1,310 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchYears is how far Next looks for a matching time. It covers the eight
+// years between two February 29th around 2100.
+const searchYears = 10
+
+// bits is a set of the values of a field, value n is bit n.
+type bits uint64
+
+func (b bits) has(n int) bool {
+	return b&(1<<uint(n)) != 0
+}
+
+// weekdayOfMonth is a day-of-week such as 4#2, the second Thursday of the
+// month, or 4L, the last Thursday of the month.
+type weekdayOfMonth struct {
+	weekday time.Weekday
+	// nth is the occurrence in the month from 1 to 5, or 0 for the last one.
+	nth int
+}
+
+func (w weekdayOfMonth) matches(t time.Time) bool {
+	if t.Weekday() != w.weekday {
+		return false
+	}
+	if w.nth == 0 {
+		return t.AddDate(0, 0, 7).Month() != t.Month()
+	}
+	return (t.Day()-1)/7+1 == w.nth
+}
+
+// Cron is a schedule given by a cron expression.
+type Cron struct {
+	minute, hour, dom, month, dow bits
+	// lastDom is true when the day-of-month contains L.
+	lastDom bool
+	// weekdays are the day-of-week values relative to the month.
+	weekdays []weekdayOfMonth
+	// domAny and dowAny are true when the field is *, days then have to
+	// match the other field only.
+	domAny, dowAny bool
+	loc            *time.Location
+}
+
+// field describes the range and the names of a cron field.
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day-of-month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{
+		"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// 7 is also Sunday
+	dowField = field{name: "day-of-week", min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+// ParseCron parses a five fields cron expression evaluated in loc.
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	c := &Cron{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+		loc:    loc,
+	}
+
+	var err error
+	if c.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if c.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if c.dom, c.lastDom, err = parseDom(fields[2]); err != nil {
+		return nil, err
+	}
+	if c.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if c.dow, c.weekdays, err = parseDow(fields[4]); err != nil {
+		return nil, err
+	}
+
+	if c.Next(time.Now()).IsZero() {
+		return nil, errors.New("the expression never matches")
+	}
+	return c, nil
+}
+
+// Next returns the first minute strictly after t matching the expression,
+// in the location of the expression. It returns the zero time when nothing
+// matches in the next years.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.In(c.loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, c.loc).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	// every loop moves to the start of the next candidate of a field, when a
+	// larger field changes meanwhile the search starts over from the month
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+
+	for !c.month.has(int(t.Month())) {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+		if t.Year() > limit {
+			return time.Time{}
+		}
+	}
+
+	for !c.dayMatches(t) {
+		month := t.Month()
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+		if t.Month() != month {
+			goto wrap
+		}
+	}
+
+	for !c.hour.has(t.Hour()) {
+		day := t.Day()
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+		if t.Day() != day {
+			goto wrap
+		}
+	}
+
+	for !c.minute.has(t.Minute()) {
+		hour := t.Hour()
+		t = t.Add(time.Minute)
+		if t.Hour() != hour {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches reports whether the day of t matches the day-of-month and the
+// day-of-week fields.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom.has(t.Day()) || (c.lastDom && t.AddDate(0, 0, 1).Day() == 1)
+	dow := c.dow.has(int(t.Weekday()))
+	for _, w := range c.weekdays {
+		dow = dow || w.matches(t)
+	}
+
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parse parses a list of values, ranges and steps.
+func (f field) parse(value string) (bits, error) {
+	var result bits
+	for part := range strings.SplitSeq(value, ",") {
+		b, err := f.parsePart(part)
+		if err != nil {
+			return 0, err
+		}
+		result |= b
+	}
+	return result, nil
+}
+
+func (f field) parsePart(part string) (bits, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step < 1 {
+			return 0, fmt.Errorf("%s: invalid step %q", f.name, stepPart)
+		}
+	}
+
+	start, end := f.min, f.max
+	if rangePart != "*" {
+		from, to, isRange := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = f.value(from); err != nil {
+			return 0, err
+		}
+		switch {
+		case isRange:
+			if end, err = f.value(to); err != nil {
+				return 0, err
+			}
+		case !hasStep:
+			end = start
+		}
+		if start > end {
+			return 0, fmt.Errorf("%s: invalid range %q", f.name, rangePart)
+		}
+	}
+
+	var result bits
+	for n := start; n <= end; n += step {
+		result |= 1 << uint(n)
+	}
+	return result, nil
+}
+
+// value parses a single number or name of the field.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: invalid value %q, expected %d-%d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// parseDom parses the day-of-month, which also accepts L.
+func parseDom(value string) (bits, bool, error) {
+	var (
+		result bits
+		last   bool
+	)
+	for part := range strings.SplitSeq(value, ",") {
+		if strings.EqualFold(part, "L") {
+			last = true
+			continue
+		}
+		b, err := domField.parsePart(part)
+		if err != nil {
+			return 0, false, err
+		}
+		result |= b
+	}
+	return result, last, nil
+}
+
+// parseDow parses the day-of-week, which also accepts 4L and 4#2.
+func parseDow(value string) (bits, []weekdayOfMonth, error) {
+	var (
+		result   bits
+		weekdays []weekdayOfMonth
+	)
+	for part := range strings.SplitSeq(value, ",") {
+		if day, nth, ok := strings.Cut(part, "#"); ok {
+			w, err := weekdayOf(day)
+			if err != nil {
+				return 0, nil, err
+			}
+			n, err := strconv.Atoi(nth)
+			if err != nil || n < 1 || n > 5 {
+				return 0, nil, fmt.Errorf("%s: invalid occurrence %q, expected 1-5", dowField.name, nth)
+			}
+			weekdays = append(weekdays, weekdayOfMonth{weekday: w, nth: n})
+			continue
+		}
+		if day, ok := strings.CutSuffix(strings.ToUpper(part), "L"); ok && day != "" {
+			w, err := weekdayOf(day)
+			if err != nil {
+				return 0, nil, err
+			}
+			weekdays = append(weekdays, weekdayOfMonth{weekday: w})
+			continue
+		}
+
+		b, err := dowField.parsePart(part)
+		if err != nil {
+			return 0, nil, err
+		}
+		result |= b
+	}
+
+	// 7 is Sunday
+	if result.has(7) {
+		result |= 1
+	}
+	return result, weekdays, nil
+}
+
+func weekdayOf(s string) (time.Weekday, error) {
+	n, err := dowField.value(s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Weekday(n % 7), nil
+}
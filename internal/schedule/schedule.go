@@ -0,0 +1,109 @@
+// Package schedule computes when recurring jobs run, from a fixed interval
+// or from cron expressions.
+//
+// A cron expression has five fields separated by spaces:
+//
+//	minute (0-59) hour (0-23) day-of-month (1-31) month (1-12 or JAN-DEC) day-of-week (0-7 or SUN-SAT)
+//
+// Each field accepts *, single values, ranges (1-5), lists (1,15) and steps
+// (*/15, 8-18/2). The day-of-month accepts L, the last day of the month, and
+// the day-of-week accepts 4L, the last Thursday of the month, and 4#2, the
+// second Thursday of the month. When both the day-of-month and the
+// day-of-week are restricted, a day matching either of them matches, like
+// cron does.
+//
+// The macros @yearly, @monthly, @weekly, @daily and @hourly are shortcuts for
+// the usual expressions, and @every 15m runs at a fixed interval.
+// Several expressions separated by semicolons run at the times of any of them,
+// such as "*/15 * * * 4L; 0 3 * * *" which runs every 15 minutes on the last
+// Thursday of the month and daily at 03:00 otherwise.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a recurring job runs next.
+type Schedule interface {
+	// Next returns the first time strictly after t at which the job runs.
+	Next(t time.Time) time.Time
+}
+
+// Every runs a job at a fixed interval.
+type Every time.Duration
+
+// Next returns t plus the interval.
+func (e Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Union runs a job at the times of any of its schedules.
+type Union []Schedule
+
+// Next returns the earliest next time of the schedules.
+func (u Union) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, s := range u {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// macros are the shortcuts for the usual cron expressions.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses one or several expressions separated by semicolons, the cron
+// expressions are evaluated in loc.
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	var union Union
+	for expr := range strings.SplitSeq(spec, ";") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		s, err := parseOne(expr, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+		union = append(union, s)
+	}
+
+	switch len(union) {
+	case 0:
+		return nil, errors.New("empty schedule")
+	case 1:
+		return union[0], nil
+	default:
+		return union, nil
+	}
+}
+
+func parseOne(expr string, loc *time.Location) (Schedule, error) {
+	if d, ok := strings.CutPrefix(expr, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, err
+		}
+		if interval < time.Minute {
+			return nil, errors.New("interval must be at least one minute")
+		}
+		return Every(interval), nil
+	}
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	return ParseCron(expr, loc)
+}
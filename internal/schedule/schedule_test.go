@@ -0,0 +1,186 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		after string
+		next  []string
+	}{
+		{
+			name:  "every 15 minutes",
+			spec:  "*/15 * * * *",
+			after: "2025-03-10T10:07:30Z",
+			next:  []string{"2025-03-10T10:15:00Z", "2025-03-10T10:30:00Z", "2025-03-10T10:45:00Z", "2025-03-10T11:00:00Z"},
+		},
+		{
+			name:  "strictly after a matching time",
+			spec:  "0 3 * * *",
+			after: "2025-03-10T03:00:00Z",
+			next:  []string{"2025-03-11T03:00:00Z", "2025-03-12T03:00:00Z"},
+		},
+		{
+			name:  "ranges, lists and names",
+			spec:  "30 8-18/5 * jan,MAR mon-fri",
+			after: "2025-03-14T17:00:00Z",
+			next:  []string{"2025-03-14T18:30:00Z", "2025-03-17T08:30:00Z", "2025-03-17T13:30:00Z"},
+		},
+		{
+			name:  "day-of-month or day-of-week",
+			spec:  "0 0 13 * 5",
+			after: "2025-06-01T00:00:00Z",
+			next:  []string{"2025-06-06T00:00:00Z", "2025-06-13T00:00:00Z", "2025-06-20T00:00:00Z", "2025-06-27T00:00:00Z"},
+		},
+		{
+			name:  "Sunday is 0 and 7",
+			spec:  "0 12 * * 7",
+			after: "2025-06-01T12:00:00Z",
+			next:  []string{"2025-06-08T12:00:00Z"},
+		},
+		{
+			name:  "last day of the month",
+			spec:  "0 0 L * *",
+			after: "2024-01-31T00:00:00Z",
+			next:  []string{"2024-02-29T00:00:00Z", "2024-03-31T00:00:00Z", "2024-04-30T00:00:00Z"},
+		},
+		{
+			name:  "last Thursday of the month",
+			spec:  "0 9 * * 4L",
+			after: "2025-01-01T00:00:00Z",
+			next:  []string{"2025-01-30T09:00:00Z", "2025-02-27T09:00:00Z", "2025-03-27T09:00:00Z"},
+		},
+		{
+			name:  "second Tuesday of the month",
+			spec:  "0 9 * * tue#2",
+			after: "2025-01-01T00:00:00Z",
+			next:  []string{"2025-01-14T09:00:00Z", "2025-02-11T09:00:00Z"},
+		},
+		{
+			name:  "February 29th",
+			spec:  "0 0 29 2 *",
+			after: "2025-01-01T00:00:00Z",
+			next:  []string{"2028-02-29T00:00:00Z", "2032-02-29T00:00:00Z"},
+		},
+		{
+			name:  "macro",
+			spec:  "@monthly",
+			after: "2025-01-15T00:00:00Z",
+			next:  []string{"2025-02-01T00:00:00Z", "2025-03-01T00:00:00Z"},
+		},
+		{
+			name:  "fixed interval",
+			spec:  "@every 90m",
+			after: "2025-01-01T00:10:00Z",
+			next:  []string{"2025-01-01T01:40:00Z", "2025-01-01T03:10:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec, time.UTC)
+			require.NoError(t, err)
+
+			at, err := time.Parse(time.RFC3339, tt.after)
+			require.NoError(t, err)
+			for _, expected := range tt.next {
+				at = s.Next(at)
+				assert.Equal(t, expected, at.UTC().Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestUnionOnPublicationDay(t *testing.T) {
+	s, err := Parse("*/15 * * * 4L; 0 3 * * *", time.UTC)
+	require.NoError(t, err)
+
+	// the day before the last Thursday of January 2025, only the daily run
+	at := s.Next(time.Date(2025, 1, 29, 4, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC), at, "publication day starts at midnight")
+
+	at = s.Next(at)
+	assert.Equal(t, time.Date(2025, 1, 30, 0, 15, 0, 0, time.UTC), at)
+
+	at = s.Next(time.Date(2025, 1, 30, 23, 50, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 1, 31, 3, 0, 0, 0, time.UTC), at, "back to daily after publication day")
+}
+
+func TestCronTimezone(t *testing.T) {
+	lisbon := mustLoadLocation(t, "Europe/Lisbon")
+	s, err := Parse("0 3 * * *", lisbon)
+	require.NoError(t, err)
+
+	// 03:00 in Lisbon is 03:00 UTC in winter and 02:00 UTC in summer
+	assert.Equal(t, time.Date(2025, 1, 10, 3, 0, 0, 0, time.UTC), s.Next(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)).UTC())
+	assert.Equal(t, time.Date(2025, 7, 10, 2, 0, 0, 0, time.UTC), s.Next(time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)).UTC())
+
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	s, err = Parse("0 0 * * *", tokyo)
+	require.NoError(t, err)
+	next := s.Next(time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC), next.UTC(), "midnight in Tokyo")
+	assert.Equal(t, tokyo, next.Location())
+}
+
+func TestCronDaylightSavingTime(t *testing.T) {
+	lisbon := mustLoadLocation(t, "Europe/Lisbon")
+
+	// on 2025-03-30 the clocks go from 01:00 to 02:00, 01:30 does not exist
+	s, err := Parse("30 1 * * *", lisbon)
+	require.NoError(t, err)
+	next := s.Next(time.Date(2025, 3, 29, 12, 0, 0, 0, lisbon))
+	assert.Equal(t, time.Date(2025, 3, 31, 1, 30, 0, 0, lisbon), next, "a time skipped by the change does not run")
+
+	// hourly runs keep running every hour across the change
+	s, err = Parse("0 * * * *", lisbon)
+	require.NoError(t, err)
+	at := time.Date(2025, 3, 30, 0, 0, 0, 0, lisbon)
+	for range 3 {
+		previous := at
+		at = s.Next(at)
+		assert.Equal(t, time.Hour, at.Sub(previous))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for spec, message := range map[string]string{
+		"":                      "empty schedule",
+		"* * * *":               "expected 5 fields",
+		"60 * * * *":            "minute: invalid value",
+		"* 24 * * *":            "hour: invalid value",
+		"* * 0 * *":             "day-of-month: invalid value",
+		"* * * 13 *":            "month: invalid value",
+		"* * * foo *":           "month: invalid value",
+		"* * * * 8":             "day-of-week: invalid value",
+		"*/0 * * * *":           "minute: invalid step",
+		"10-5 * * * *":          "minute: invalid range",
+		"* * * * 4#6":           "day-of-week: invalid occurrence",
+		"0 0 30 2 *":            "never matches",
+		"@every soon":           "invalid duration",
+		"@every 10s":            "at least one minute",
+		"0 3 * * *; 61 * * * *": "minute: invalid value",
+	} {
+		_, err := Parse(spec, time.UTC)
+		assert.ErrorContains(t, err, message, spec)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, at.Add(time.Hour), Every(time.Hour).Next(at))
+}
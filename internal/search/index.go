@@ -0,0 +1,211 @@
+// Package search implements a small in-memory full-text index.
+//
+// Documents are split into case-folded tokens and ranked with BM25, title
+// matches weigh more than description matches. The index is immutable, it
+// is meant to be rebuilt whenever the indexed documents change.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// BM25 parameters, see https://en.wikipedia.org/wiki/Okapi_BM25
+	k1 = 1.2
+	b  = 0.75
+
+	// titleWeight is how many times a title token counts more than a
+	// description token.
+	titleWeight = 3.0
+
+	// prefixWeight is applied to matches of the last query token as a prefix,
+	// so results show up while the user is still typing a word.
+	prefixWeight = 0.5
+)
+
+// Document is the text of an indexed item.
+type Document struct {
+	Title       string
+	Description string
+}
+
+// Result is a document matching a query.
+type Result struct {
+	// Doc is the position of the document in the slice given to NewIndex.
+	Doc   int
+	Score float64
+	// matched is the number of query tokens found in the document.
+	matched int
+}
+
+type posting struct {
+	doc int
+	// freq is the weighted frequency of the term in the document.
+	freq float64
+}
+
+// Index is an inverted index over a list of documents.
+type Index struct {
+	postings map[string][]posting
+	// terms is the sorted vocabulary, used for prefix lookups.
+	terms     []string
+	docLength []float64
+	avgLength float64
+}
+
+// NewIndex builds an index over docs.
+func NewIndex(docs []Document) *Index {
+	idx := &Index{
+		postings:  map[string][]posting{},
+		docLength: make([]float64, len(docs)),
+	}
+
+	var totalLength float64
+	for i, d := range docs {
+		freqs := map[string]float64{}
+		for _, t := range Tokenize(d.Title) {
+			freqs[t] += titleWeight
+		}
+		for _, t := range Tokenize(d.Description) {
+			freqs[t]++
+		}
+
+		for t, f := range freqs {
+			idx.postings[t] = append(idx.postings[t], posting{doc: i, freq: f})
+			idx.docLength[i] += f
+		}
+		totalLength += idx.docLength[i]
+	}
+
+	if len(docs) > 0 {
+		idx.avgLength = totalLength / float64(len(docs))
+	}
+
+	idx.terms = make([]string, 0, len(idx.postings))
+	for t := range idx.postings {
+		idx.terms = append(idx.terms, t)
+	}
+	sort.Strings(idx.terms)
+
+	return idx
+}
+
+// Search returns the documents matching query, best match first.
+// Documents matching more of the query tokens always rank above documents
+// matching fewer of them. Results with the same score keep the document order.
+func (idx *Index) Search(query string) []Result {
+	tokens := unique(Tokenize(query))
+	if len(tokens) == 0 {
+		return []Result{}
+	}
+
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	for i, token := range tokens {
+		weights := map[string]float64{token: 1}
+		if i == len(tokens)-1 {
+			for _, t := range idx.withPrefix(token) {
+				if t != token {
+					weights[t] = prefixWeight
+				}
+			}
+		}
+
+		seen := map[int]bool{}
+		for term, weight := range weights {
+			for doc, score := range idx.score(term) {
+				scores[doc] += weight * score
+				seen[doc] = true
+			}
+		}
+		for doc := range seen {
+			matched[doc]++
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for doc, score := range scores {
+		results = append(results, Result{Doc: doc, Score: score, matched: matched[doc]})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].matched != results[j].matched {
+			return results[i].matched > results[j].matched
+		}
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc < results[j].Doc
+	})
+	return results
+}
+
+// score computes the BM25 score of term for every document containing it.
+func (idx *Index) score(term string) map[int]float64 {
+	postings := idx.postings[term]
+	result := make(map[int]float64, len(postings))
+	if len(postings) == 0 {
+		return result
+	}
+
+	n := float64(len(idx.docLength))
+	df := float64(len(postings))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	for _, p := range postings {
+		norm := k1 * (1 - b + b*idx.docLength[p.doc]/idx.avgLength)
+		result[p.doc] = idf * p.freq * (k1 + 1) / (p.freq + norm)
+	}
+	return result
+}
+
+// withPrefix returns every indexed term starting with prefix.
+func (idx *Index) withPrefix(prefix string) []string {
+	start := sort.SearchStrings(idx.terms, prefix)
+	end := start
+	for end < len(idx.terms) && strings.HasPrefix(idx.terms[end], prefix) {
+		end++
+	}
+	return idx.terms[start:end]
+}
+
+// Tokenize splits s into lower case tokens made of letters and digits.
+func Tokenize(s string) []string {
+	var (
+		tokens  []string
+		current strings.Builder
+	)
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func unique(tokens []string) []string {
+	seen := map[string]bool{}
+	result := tokens[:0]
+	for _, t := range tokens {
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result
+}
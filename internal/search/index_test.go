@@ -0,0 +1,57 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var docs = []Document{
+	{Title: "The MagPi issue 140", Description: "Build a robot arm with a Raspberry Pi Pico W"},
+	{Title: "Robot Arm Projects", Description: "Everything about robots"},
+	{Title: "Get started with MicroPython on Raspberry Pi Pico", Description: "Learn to code the Pico"},
+	{Title: "The MagPi issue 141", Description: "Home automation and a weather station"},
+	{Title: "Code the Classics", Description: "Arm yourself with retro games"},
+}
+
+func docIDs(results []Result) []int {
+	ids := make([]int, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Doc)
+	}
+	return ids
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"pico", "w", "robot", "arm", "2025"}, Tokenize("Pico-W: ROBOT arm (2025)!"))
+	assert.Empty(t, Tokenize(" -- "))
+}
+
+func TestSearchRanksTitleMatchesFirst(t *testing.T) {
+	idx := NewIndex(docs)
+
+	// both match every token, the title match wins
+	assert.Equal(t, []int{1, 0, 4}, docIDs(idx.Search("robot arm")))
+}
+
+func TestSearchIsCaseInsensitive(t *testing.T) {
+	idx := NewIndex(docs)
+
+	assert.Equal(t, docIDs(idx.Search("pico w")), docIDs(idx.Search("PICO W")))
+	assert.Equal(t, 0, idx.Search("Pico W")[0].Doc, "only the first document matches both tokens")
+}
+
+func TestSearchMatchesPrefixOfLastToken(t *testing.T) {
+	idx := NewIndex(docs)
+
+	assert.Equal(t, []int{3}, docIDs(idx.Search("weath")))
+	assert.Empty(t, idx.Search("weath xyz"), "prefixes only apply to the last token")
+}
+
+func TestSearchNoMatches(t *testing.T) {
+	idx := NewIndex(docs)
+
+	assert.Empty(t, idx.Search("zx spectrum"))
+	assert.Empty(t, idx.Search("   "))
+	assert.Empty(t, NewIndex(nil).Search("pico"))
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/brunofjesus/raspberry-bookshelf/internal/bookshelf"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend/handlers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/metrics"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/mirror"
+)
+
+// fetchBuckets are the upper bounds, in seconds, of the source fetch
+// duration buckets. Fetches are much slower than the HTTP requests served.
+var fetchBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// sourceMetrics measures the fetches of the catalog sources.
+type sourceMetrics struct {
+	duration    *metrics.Histogram
+	fetches     *metrics.Counter
+	lastSuccess *metrics.Gauge
+}
+
+func newSourceMetrics(registry *metrics.Registry) *sourceMetrics {
+	return &sourceMetrics{
+		duration: registry.NewHistogram(
+			"bookshelf_source_fetch_duration_seconds",
+			"Duration of the fetches of the catalog sources.",
+			fetchBuckets,
+			"source",
+		),
+		fetches: registry.NewCounter(
+			"bookshelf_source_fetches_total",
+			"Number of fetches of the catalog sources by result, success, unchanged or failure.",
+			"source", "result",
+		),
+		lastSuccess: registry.NewGauge(
+			"bookshelf_source_last_success_timestamp_seconds",
+			"Time of the last successful fetch of the catalog sources.",
+			"source",
+		),
+	}
+}
+
+// instrument wraps the client of a source so its fetches are measured.
+func (m *sourceMetrics) instrument(source string, client bookshelf.BookClient) bookshelf.BookClient {
+	return &instrumentedClient{source: source, client: client, metrics: m}
+}
+
+// instrumentedClient is a BookClient measuring the fetches of a source.
+type instrumentedClient struct {
+	source  string
+	client  bookshelf.BookClient
+	metrics *sourceMetrics
+}
+
+func (c *instrumentedClient) GetBooks(ctx context.Context) ([]entities.Book, error) {
+	start := time.Now()
+	books, err := c.client.GetBooks(ctx)
+	c.metrics.duration.Observe(time.Since(start).Seconds(), c.source)
+
+	result := "success"
+	switch {
+	case errors.Is(err, entities.ErrUnchanged):
+		result = "unchanged"
+	case err != nil:
+		result = "failure"
+	}
+	c.metrics.fetches.Inc(c.source, result)
+	if result != "failure" {
+		c.metrics.lastSuccess.Set(float64(time.Now().Unix()), c.source)
+	}
+	return books, err
+}
+
+// registerCatalogMetrics registers the metrics read from the updater, the
+// catalog and the mirror when they are scraped. The mirror is nil when it
+// is disabled.
+func registerCatalogMetrics(
+	registry *metrics.Registry,
+	updater *bookshelf.BookshelfUpdater,
+	storage *bookshelf.PersistentStorage,
+	bookMirror *mirror.Manager,
+) {
+	lastSuccess := registry.NewGauge(
+		"bookshelf_refresh_last_success_timestamp_seconds",
+		"Time of the last successful refresh of the catalog.",
+	)
+	failures := registry.NewGauge(
+		"bookshelf_refresh_consecutive_failures",
+		"Number of refreshes that failed since the last successful one.",
+	)
+	books := registry.NewGauge(
+		"bookshelf_books",
+		"Number of books in the catalog by category.",
+		"category",
+	)
+	registry.OnScrape(func(ctx context.Context) {
+		status, err := updater.Status(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot get the updater status", slog.Any("error", err))
+			return
+		}
+		if !status.LastSuccess.IsZero() {
+			lastSuccess.Set(float64(status.LastSuccess.Unix()))
+		}
+		failures.Set(float64(status.Failures))
+
+		catalog, err := storage.Get(ctx, entities.BookQuery{})
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot get the books", slog.Any("error", err))
+			return
+		}
+		counts := map[string]int{}
+		for _, b := range catalog.Books {
+			counts[b.Category]++
+		}
+		books.Reset()
+		for category, n := range counts {
+			books.Set(float64(n), category)
+		}
+	})
+
+	if bookMirror == nil {
+		return
+	}
+	usage := registry.NewGauge(
+		"bookshelf_mirror_usage_bytes",
+		"Size of the mirrored PDFs.",
+	)
+	registry.OnScrape(func(context.Context) {
+		usage.Set(float64(bookMirror.Usage()))
+	})
+}
+
+// countDownloads wraps the lookup of the mirrored PDFs, which is made for
+// every download of an existing book, to count the downloads served from
+// the mirror, the ones redirected to the files of a directory source and the
+// ones redirected upstream.
+func countDownloads(registry *metrics.Registry, getBook handlers.GetBookFn, lookupMirrored handlers.LookupMirroredFn) handlers.LookupMirroredFn {
+	downloads := registry.NewCounter(
+		"bookshelf_downloads_total",
+		"Number of book downloads by origin, mirror, local or upstream.",
+		"served",
+	)
+	return func(ctx context.Context, bookID string) (string, bool) {
+		filePath, ok := lookupMirrored(ctx, bookID)
+		if ok {
+			downloads.Inc("mirror")
+			return filePath, ok
+		}
+		if book, err := getBook(ctx, bookID); err == nil && book != nil && !entities.IsRemote(book.Link) {
+			downloads.Inc("local")
+		} else {
+			downloads.Inc("upstream")
+		}
+		return filePath, ok
+	}
+}
@@ -2,48 +2,197 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"path/filepath"
 	"time"
 
 	"github.com/brunofjesus/raspberry-bookshelf/internal/adapters"
 	"github.com/brunofjesus/raspberry-bookshelf/internal/bookshelf"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/config"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/covers"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/entities"
 	"github.com/brunofjesus/raspberry-bookshelf/internal/frontend"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/metrics"
+	"github.com/brunofjesus/raspberry-bookshelf/internal/mirror"
 	"golang.org/x/sync/errgroup"
 )
 
-// Runner defines an interface for components that can be run.
-type Runner interface {
-	Run(ctx context.Context) error
-}
+// defaultPollInterval is the time between two checks of a directory source
+// for changes, when it is not configured.
+const defaultPollInterval = time.Minute
 
 // Service represents the main application service.
 // It holds the data needed for the application to run.
 type Service struct {
-	bookUpdater Runner
-	bookStorage *bookshelf.Storage
+	config      config.Config
+	bookUpdater *bookshelf.BookshelfUpdater
+	bookClient  *bookshelf.MultiSourceClient
+	directories map[string]directorySource
+	bookStorage *bookshelf.PersistentStorage
+	changeLog   *bookshelf.ChangeLog
+	mirror      *mirror.Manager
+	covers      *covers.Manager
+	metrics     *metrics.Registry
 }
 
 // New creates a new instance of the Service.
 // It initializes the necessary components such as the book client,
-// book storage, and book updater.
-func New() Service {
-	bookClient := adapters.NewMagPiAPI()
-	bookStorage := bookshelf.NewStorage()
+// book storage, and book updater, from the given configuration.
+// The last persisted catalog is loaded so the bookshelf can be served
+// before the first refresh completes.
+func New(ctx context.Context, cfg config.Config) Service {
+	registry := metrics.NewRegistry()
+	bookClient, directories := newBookClient(cfg, newSourceMetrics(registry))
+	bookStorage := bookshelf.NewPersistentStorage(cfg.Storage.DataDir)
+	if err := bookStorage.Load(ctx); err != nil {
+		slog.ErrorContext(ctx, "cannot load persisted catalog", slog.Any("error", err))
+	}
+	if catalog, err := bookStorage.Get(ctx, entities.BookQuery{}); err == nil {
+		bookClient.Restore(catalog.Books)
+	}
+
+	changeLog := bookshelf.NewChangeLog(cfg.Storage.DataDir)
+	if err := changeLog.Load(ctx); err != nil {
+		slog.ErrorContext(ctx, "cannot load the change log", slog.Any("error", err))
+	}
 
+	// the schedule was already checked by the configuration validation
+	refreshSchedule, _ := cfg.Updater.RefreshSchedule()
 	updater := bookshelf.NewBookshelfUpdater(
 		bookClient,
 		bookStorage,
-		1*time.Hour,
+		changeLog,
+		refreshSchedule,
+		bookshelf.Backoff{
+			Initial:    cfg.Updater.Retry.InitialInterval,
+			Max:        cfg.Updater.Retry.MaxInterval,
+			Multiplier: cfg.Updater.Retry.Multiplier,
+			Jitter:     cfg.Updater.Retry.Jitter,
+		},
 	)
 
+	var bookMirror *mirror.Manager
+	if cfg.Mirror.Enabled {
+		dir := cfg.Mirror.Dir
+		if dir == "" {
+			dir = filepath.Join(cfg.Storage.DataDir, "mirror")
+		}
+
+		var err error
+		bookMirror, err = mirror.NewManager(bookStorage, mirror.Options{
+			Dir:         dir,
+			Concurrency: cfg.Mirror.Concurrency,
+			Quota:       int64(cfg.Mirror.Quota),
+			Interval:    cfg.Mirror.Interval,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot start the mirror, PDFs are served from upstream", slog.Any("error", err))
+		}
+	}
+
+	var coverCache *covers.Manager
+	if cfg.Covers.Enabled {
+		dir := cfg.Covers.Dir
+		if dir == "" {
+			dir = filepath.Join(cfg.Storage.DataDir, "covers")
+		}
+
+		var err error
+		coverCache, err = covers.NewManager(bookStorage, covers.Options{
+			Dir:         dir,
+			Concurrency: cfg.Covers.Concurrency,
+			Interval:    cfg.Covers.Interval,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot start the cover cache, covers are served from upstream", slog.Any("error", err))
+		}
+	}
+
+	registerCatalogMetrics(registry, updater, bookStorage, bookMirror)
+
 	return Service{
+		config:      cfg,
 		bookUpdater: updater,
+		bookClient:  bookClient,
+		directories: directories,
 		bookStorage: bookStorage,
+		changeLog:   changeLog,
+		mirror:      bookMirror,
+		covers:      coverCache,
+		metrics:     registry,
 	}
 }
 
+// directorySource is a local directory source, watched for changes and
+// whose files are served by the application.
+type directorySource struct {
+	api          *adapters.DirectoryAPI
+	pollInterval time.Duration
+}
+
+// newBookClient creates the client aggregating the built-in MagPi source
+// and the configured sources. The directory sources are also returned by
+// name. The fetches of every source are measured.
+func newBookClient(cfg config.Config, sourceMetrics *sourceMetrics) (*bookshelf.MultiSourceClient, map[string]directorySource) {
+	directories := map[string]directorySource{}
+	sources := []bookshelf.Source{
+		{
+			Name:   "magpi",
+			Client: sourceMetrics.instrument("magpi", adapters.NewMagPiAPI(cfg.MagPi.URL, cfg.MagPi.Timeout)),
+		},
+	}
+	for _, source := range cfg.Sources {
+		timeout := source.Timeout
+		if timeout == 0 {
+			timeout = cfg.MagPi.Timeout
+		}
+
+		var client bookshelf.BookClient
+		switch source.Type {
+		case config.SourceTypeMagPi:
+			client = adapters.NewMagPiAPI(source.URL, timeout)
+		case config.SourceTypeFeed:
+			client = adapters.NewFeedAPI(source.URL, source.Category, timeout)
+		case config.SourceTypeDirectory:
+			pollInterval := source.PollInterval
+			if pollInterval == 0 {
+				pollInterval = defaultPollInterval
+			}
+			directory := adapters.NewDirectoryAPI(source.Name, source.Path)
+			directories[source.Name] = directorySource{api: directory, pollInterval: pollInterval}
+			client = directory
+		default:
+			slog.Error("ignoring source of unknown type",
+				slog.String("source", source.Name),
+				slog.String("type", source.Type),
+			)
+			continue
+		}
+
+		sources = append(sources, bookshelf.Source{
+			Name:      source.Name,
+			Namespace: source.Namespace,
+			Client:    sourceMetrics.instrument(source.Name, client),
+		})
+	}
+	return bookshelf.NewMultiSourceClient(sources...), directories
+}
+
+// lookupLocalFile returns the path of a file served by a directory source.
+func (s Service) lookupLocalFile(_ context.Context, source, name string) (string, bool) {
+	directory, ok := s.directories[source]
+	if !ok {
+		return "", false
+	}
+	return directory.api.Lookup(name)
+}
+
 // Run starts the service, including the book updater and the HTTP web server.
+// When ctx is done the HTTP server stops accepting connections and waits,
+// up to the configured shutdown timeout, for in-flight requests to complete.
 func (s Service) Run(ctx context.Context) error {
 	g, ctx := errgroup.WithContext(ctx)
 	g.Go(func() error {
@@ -51,14 +200,86 @@ func (s Service) Run(ctx context.Context) error {
 		return s.bookUpdater.Run(ctx)
 	})
 
+	for name, directory := range s.directories {
+		g.Go(func() error {
+			slog.Debug("Watching the directory source", slog.String("source", name))
+			return directory.api.Watch(ctx, directory.pollInterval, s.bookUpdater.Refresh)
+		})
+	}
+
+	lookupMirrored := func(context.Context, string) (string, bool) { return "", false }
+	if s.mirror != nil {
+		lookupMirrored = s.mirror.Lookup
+		g.Go(func() error {
+			slog.Debug("Starting the PDF mirror")
+			return s.mirror.Run(ctx)
+		})
+	}
+
+	lookupCover := func(context.Context, entities.Book, covers.Size) (string, bool) { return "", false }
+	if s.covers != nil {
+		lookupCover = s.covers.Lookup
+		g.Go(func() error {
+			slog.Debug("Starting the cover cache")
+			return s.covers.Run(ctx)
+		})
+	}
+
+	if s.config.Admin.Token == "" {
+		slog.Info("The admin endpoints are disabled, set an admin token to enable them")
+	}
+
+	server := &http.Server{
+		Addr: s.config.Server.Address,
+		Handler: frontend.NewHTTPRouter(frontend.Dependencies{
+			GetCategories:   s.bookStorage.GetCategories,
+			GetBook:         s.bookStorage.GetByID,
+			ResolveAlias:    s.bookStorage.ResolveAlias,
+			GetBooks:        s.bookStorage.Get,
+			SearchBooks:     s.bookStorage.Search,
+			LookupMirrored:  countDownloads(s.metrics, s.bookStorage.GetByID, lookupMirrored),
+			LookupCover:     lookupCover,
+			GetChanges:      s.changeLog.Recent,
+			GetSources:      s.bookClient.Health,
+			LookupLocalFile: s.lookupLocalFile,
+			AdminToken:      s.config.Admin.Token,
+			Refresh:         s.bookUpdater.Refresh,
+			GetStatus:       s.bookUpdater.Status,
+			GetSnapshotTime: s.bookStorage.LoadedSavedAt,
+			StaleAfter:      s.config.Updater.StaleAfter,
+			Registry:        s.metrics,
+		}),
+	}
+
+	g.Go(func() error {
+		slog.Debug("Starting the HTTP Web Server", slog.String("address", server.Addr))
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+
 	g.Go(func() error {
-		slog.Debug("Starting the HTTP Web Server")
-		router := frontend.NewHTTPRouter(
-			s.bookStorage.GetCategories,
-			s.bookStorage.GetByID,
-			s.bookStorage.Get,
+		<-ctx.Done()
+		slog.Info("Shutting down the HTTP Web Server",
+			slog.Duration("timeout", s.config.Server.ShutdownTimeout),
+		)
+		shutdownCtx, cancel := context.WithTimeout(
+			context.WithoutCancel(ctx),
+			s.config.Server.ShutdownTimeout,
 		)
-		return http.ListenAndServe("0.0.0.0:8080", router)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Error("In-flight requests did not complete before the shutdown timeout, closing their connections",
+				slog.Duration("timeout", s.config.Server.ShutdownTimeout),
+			)
+			return errors.Join(
+				fmt.Errorf("in-flight requests did not complete within %s", s.config.Server.ShutdownTimeout),
+				server.Close(),
+			)
+		}
+		return err
 	})
 
 	// Block until all goroutines finish